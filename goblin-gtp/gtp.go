@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/risboo6909/goblin/misc"
+)
+
+const (
+	gtpName    = "goblin"
+	gtpVersion = "0.1"
+)
+
+// gtpError is a failure reported to a controller
+type gtpError string
+
+func (e gtpError) Error() string {
+	return string(e)
+}
+
+type handler func(g *gtp, args []string) (string, error)
+
+var handlers map[string]handler
+
+func init() {
+	handlers = map[string]handler{
+		"protocol_version": func(*gtp, []string) (string, error) { return "2", nil },
+		"name":             func(*gtp, []string) (string, error) { return gtpName, nil },
+		"version":          func(*gtp, []string) (string, error) { return gtpVersion, nil },
+		"known_command":    knownCommand,
+		"list_commands":    listCommands,
+		"quit":             func(*gtp, []string) (string, error) { return "", nil },
+		"boardsize":        boardSize,
+		"clear_board":      clearBoard,
+		"komi":             func(*gtp, []string) (string, error) { return "", nil },
+		"play":             play,
+		"genmove":          genMove,
+		"undo":             undo,
+		"showboard":        showBoard,
+		"final_score":      finalScore,
+	}
+}
+
+type gtp struct {
+	out     io.Writer
+	session *misc.Session
+
+	winLength int
+	depth     int
+	moveTime  time.Duration
+}
+
+func newGTP(out io.Writer, boardSide, winLength, depth int, moveTime time.Duration) *gtp {
+	g := &gtp{out: out, winLength: winLength, depth: depth, moveTime: moveTime}
+	g.newSession(boardSide)
+	return g
+}
+
+// newSession starts a game where both sides are played by engine, moves
+// sent by controller are relayed using PlayAs
+func (g *gtp) newSession(boardSide int) {
+
+	options := misc.SessionOptions{BoardSide: boardSide, WinLength: g.winLength, Rules: misc.Freestyle,
+		PlayerX: misc.EnginePlayer("Black", g.depth), PlayerO: misc.EnginePlayer("White", g.depth)}
+
+	options.PlayerX.AI.TimeLimit = g.moveTime
+	options.PlayerO.AI.TimeLimit = g.moveTime
+
+	g.session = misc.NewSession(options)
+}
+
+// setTurn makes player the side to move unless the game is over
+func (g *gtp) setTurn(player misc.Cell) {
+	if !g.session.Over() {
+		g.session.Turn = player
+	}
+}
+
+func (g *gtp) notation() misc.Notation {
+	return misc.Notation{Rows: g.session.Board.CellsVert, Origin: misc.BottomLeft, SkipI: true}
+}
+
+// run executes commands until quit or end of input
+func (g *gtp) run(in io.Reader) {
+
+	scanner := bufio.NewScanner(in)
+
+	for scanner.Scan() {
+
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		id := ""
+		if _, err := strconv.Atoi(fields[0]); err == nil {
+			id, fields = fields[0], fields[1:]
+		}
+
+		if len(fields) == 0 {
+			fmt.Fprintf(g.out, "?%s empty command\n\n", id)
+			continue
+		}
+
+		command := strings.ToLower(fields[0])
+
+		h, found := handlers[command]
+		if !found {
+			fmt.Fprintf(g.out, "?%s unknown command\n\n", id)
+			continue
+		}
+
+		result, err := h(g, fields[1:])
+
+		if err != nil {
+			fmt.Fprintf(g.out, "?%s %v\n\n", id, err)
+		} else if result == "" {
+			fmt.Fprintf(g.out, "=%s\n\n", id)
+		} else {
+			fmt.Fprintf(g.out, "=%s %s\n\n", id, result)
+		}
+
+		if command == "quit" {
+			return
+		}
+	}
+}
+
+func parseColor(s string) (misc.Cell, error) {
+	switch strings.ToLower(s) {
+	case "b", "black":
+		return misc.X, nil
+	case "w", "white":
+		return misc.O, nil
+	}
+	return misc.E, gtpError("invalid color")
+}
+
+func knownCommand(g *gtp, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", gtpError("syntax error")
+	}
+	_, found := handlers[args[0]]
+	return strconv.FormatBool(found), nil
+}
+
+func listCommands(g *gtp, args []string) (string, error) {
+	commands := make([]string, 0, len(handlers))
+	for command := range handlers {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	return strings.Join(commands, "\n"), nil
+}
+
+func boardSize(g *gtp, args []string) (string, error) {
+
+	if len(args) != 1 {
+		return "", gtpError("syntax error")
+	}
+
+	size, err := strconv.Atoi(args[0])
+	if err != nil {
+		return "", gtpError("syntax error")
+	}
+
+	if size < g.winLength || size > misc.MaxBoardSide {
+		return "", gtpError("unacceptable size")
+	}
+
+	g.newSession(size)
+	return "", nil
+}
+
+func clearBoard(g *gtp, args []string) (string, error) {
+	g.newSession(g.session.Board.CellsHoriz)
+	return "", nil
+}
+
+func play(g *gtp, args []string) (string, error) {
+
+	if len(args) != 2 {
+		return "", gtpError("syntax error")
+	}
+
+	player, err := parseColor(args[0])
+	if err != nil {
+		return "", err
+	}
+
+	pos, err := g.notation().Parse(args[1])
+	if err == nil {
+		_, err = g.session.Board.ToLinear(pos.Col, pos.Row)
+	}
+	if err != nil {
+		return "", gtpError("invalid vertex")
+	}
+
+	// GTP allows consecutive moves of one color, controllers set up positions so
+	g.setTurn(player)
+
+	if err := g.session.PlayAs(player, pos); err != nil {
+		return "", gtpError("illegal move: " + err.Error())
+	}
+
+	return "", nil
+}
+
+func genMove(g *gtp, args []string) (string, error) {
+
+	if len(args) != 1 {
+		return "", gtpError("syntax error")
+	}
+
+	player, err := parseColor(args[0])
+	if err != nil {
+		return "", err
+	}
+
+	// there is nothing to play after the game end, the loser resigns
+	if g.session.Over() {
+		if g.session.Status != misc.GameDraw && g.session.Winner != player {
+			return "resign", nil
+		}
+		return "pass", nil
+	}
+
+	g.setTurn(player)
+
+	if err := g.session.MakeMove(); err != nil {
+		return "", gtpError(err.Error())
+	}
+
+	last := g.session.History[len(g.session.History)-1]
+	return g.notation().Format(last.Pos), nil
+}
+
+func undo(g *gtp, args []string) (string, error) {
+	if err := g.session.Undo(); err != nil {
+		return "", gtpError("cannot undo")
+	}
+	return "", nil
+}
+
+// showBoard prints board with GTP coordinates, black stones are X and white are O
+func showBoard(g *gtp, args []string) (string, error) {
+
+	board := g.session.Board
+	notation := g.notation()
+
+	header := "  "
+	for col := 0; col < board.CellsHoriz; col++ {
+		header += " " + notation.ColumnName(col)
+	}
+
+	lines := []string{"", header}
+
+	for row := 0; row < board.CellsVert; row++ {
+		line := fmt.Sprintf("%2s", notation.RowName(row))
+		for col := 0; col < board.CellsHoriz; col++ {
+			if cell := board.GetCell(col, row); cell != misc.E {
+				line += " " + string(cell)
+			} else {
+				line += " ."
+			}
+		}
+		lines = append(lines, line+" "+notation.RowName(row))
+	}
+
+	lines = append(lines, header)
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func finalScore(g *gtp, args []string) (string, error) {
+
+	switch g.session.Status {
+	case misc.GameWon:
+		if g.session.Winner == misc.X {
+			return "B+", nil
+		}
+		return "W+", nil
+	case misc.GameDraw:
+		return "0", nil
+	}
+
+	return "", gtpError("cannot score")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// exchange runs commands and returns responses without trailing empty lines,
+// engine is depth limited and untimed, so that its moves don't depend on speed
+func exchange(commands string) []string {
+
+	var out bytes.Buffer
+
+	g := newGTP(&out, 15, 5, 1, 0)
+	g.run(strings.NewReader(commands))
+
+	return strings.Split(strings.TrimSuffix(out.String(), "\n\n"), "\n\n")
+}
+
+func expectResponses(t *testing.T, commands string, expected ...string) {
+
+	responses := exchange(commands)
+
+	if len(responses) != len(expected) {
+		t.Fatalf("Expected %d responses, got %q", len(expected), responses)
+	}
+
+	for i, response := range responses {
+		if response != expected[i] {
+			t.Fatalf("Expected %q, got %q", expected[i], response)
+		}
+	}
+}
+
+func TestGTPAdministrative(t *testing.T) {
+
+	expectResponses(t, "1 protocol_version\n2 name\nversion\n# comment\n\n3 known_command genmove\n4 known_command fly\n",
+		"=1 2", "=2 goblin", "= 0.1", "=3 true", "=4 false")
+
+	expectResponses(t, "10 foo\n11 quit\nname\n", "?10 unknown command", "=11")
+
+	responses := exchange("list_commands\n")
+	if !strings.Contains(responses[0], "\ngenmove\n") || !strings.HasPrefix(responses[0], "= ") {
+		t.Fatalf("Unexpected list of commands %q", responses[0])
+	}
+}
+
+func TestGTPPlay(t *testing.T) {
+
+	expectResponses(t, "1 play black H8\n2 play white H8\n3 play black J9\n4 play black J9\n5 play w Z1\n6 play red A1\n7 play b I1\n",
+		"=1", "?2 illegal move: cell is already occupied", "=3",
+		"?4 illegal move: cell is already occupied",
+		"?5 invalid vertex", "?6 invalid color", "?7 invalid vertex")
+
+	// positions are set up by consecutive moves of one color, undo returns the turn to its mover
+	responses := exchange("play b A1\nplay b B1\nplay b C1\nplay b D1\nplay w A5\nplay w B5\nundo\ngenmove b\nfinal_score\n")
+
+	for i, response := range responses[:7] {
+		if response != "=" {
+			t.Fatalf("Unexpected response %d %q", i, response)
+		}
+	}
+	if responses[7] != "= E1" || responses[8] != "= B+" {
+		t.Fatalf("Engine has missed the win: %q", responses[7:])
+	}
+
+	expectResponses(t, "boardsize 3\nboardsize 26\nboardsize x\n5 boardsize 19\nplay b T19\nplay w T1\n",
+		"? unacceptable size", "? unacceptable size", "? syntax error", "=5", "=", "=")
+}
+
+func TestGTPGenMove(t *testing.T) {
+
+	// engine opens in the center
+	expectResponses(t, "1 genmove b\n2 undo\n3 undo\n4 genmove black\n",
+		"=1 H8", "=2", "?3 cannot undo", "=4 H8")
+
+	// either side may be asked to move
+	responses := exchange("1 genmove b\n2 genmove b\n3 showboard\n")
+	if responses[0] != "=1 H8" || !strings.HasPrefix(responses[1], "=2 ") || strings.Count(responses[2], "X") != 2 {
+		t.Fatalf("Unexpected responses to consecutive genmove %q", responses)
+	}
+
+	// black has four in a row and completes five
+	responses = exchange("play b A1\nplay w A2\nplay b B1\nplay w B2\nplay b C1\nplay w C2\nplay b D1\nplay w D2\ngenmove b\nfinal_score\ngenmove w\ngenmove b\n")
+
+	if responses[8] != "= E1" {
+		t.Fatalf("Engine has missed the win: %q", responses[8])
+	}
+	if responses[9] != "= B+" || responses[10] != "= resign" || responses[11] != "= pass" {
+		t.Fatalf("Unexpected responses after the game end %q", responses[9:])
+	}
+}
+
+func TestGTPShowBoard(t *testing.T) {
+
+	responses := exchange("boardsize 5\nplay b A1\nplay w E5\nshowboard\nfinal_score\nclear_board\nshowboard\n")
+
+	expected := `= 
+   A B C D E
+ 5 . . . . O 5
+ 4 . . . . . 4
+ 3 . . . . . 3
+ 2 . . . . . 2
+ 1 X . . . . 1
+   A B C D E`
+
+	if responses[3] != expected {
+		t.Fatalf("Unexpected board:\n%v", responses[3])
+	}
+
+	if responses[4] != "? cannot score" || strings.Contains(responses[6], "X") {
+		t.Fatalf("Unexpected responses %q", responses[4:])
+	}
+}
@@ -0,0 +1,23 @@
+// goblin-gtp drives goblin using a Go Text Protocol like line protocol on stdin and stdout
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+)
+
+var (
+	boardSide = flag.Int("size", 15, "initial board size")
+	winLength = flag.Int("win", 5, "number of stones in a row required to win")
+	depth     = flag.Int("depth", 0, "engine search depth, the default one if zero")
+	moveTime  = flag.Duration("time", 5*time.Second, "time limit for generated moves")
+)
+
+func main() {
+
+	flag.Parse()
+
+	engine := newGTP(os.Stdout, *boardSide, *winLength, *depth, *moveTime)
+	engine.run(os.Stdin)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/risboo6909/goblin/misc"
+	"github.com/risboo6909/goblin/ui"
+)
+
+// tally counts results of games played by the local player during this run
+type tally struct {
+	Wins, Losses, Draws int
+}
+
+func (t tally) String() string {
+	return fmt.Sprintf("Wins %d  Losses %d  Draws %d", t.Wins, t.Losses, t.Draws)
+}
+
+var (
+	score tally
+
+	gameStarted time.Time
+	gameEnded   time.Time
+
+	// true once the game over screen is shown for the current game
+	resultSeen bool
+
+	// true once the result of the current game is added to the tally,
+	// results of loaded games are not counted
+	resultCounted bool
+
+	gameOverMenu *ui.Menu
+
+	// number of moves shown in review
+	reviewMove int
+)
+
+// localSide returns the side of the only local human player, E if both or
+// none of the players are local humans
+func localSide() misc.Cell {
+
+	if netGame != nil {
+		return netGame.Local
+	}
+
+	x, o := gameSession.PlayerX.Kind == misc.Human, gameSession.PlayerO.Kind == misc.Human
+
+	switch {
+	case x && !o:
+		return misc.X
+	case o && !x:
+		return misc.O
+	}
+
+	return misc.E
+}
+
+// countResult adds result of the finished game to the tally
+func countResult() {
+
+	side := localSide()
+
+	switch {
+	case side == misc.E:
+	case gameSession.Status == misc.GameDraw:
+		score.Draws++
+	case gameSession.Winner == side:
+		score.Wins++
+	default:
+		score.Losses++
+	}
+}
+
+// checkGameOver shows the game over screen when the game has just ended and
+// hides it if the game is resumed by undo
+func checkGameOver() {
+
+	switch {
+
+	case gameState == StateGameplay && gameSession.Over() && !resultSeen:
+		resultSeen = true
+		// a game resumed by undo may end again, its duration runs to the latest end,
+		// games loaded finished have no duration
+		if !resultCounted || !gameEnded.IsZero() {
+			gameEnded = time.Now()
+		}
+		if !resultCounted {
+			resultCounted = true
+			countResult()
+		}
+		showGameOver()
+
+	case (gameState == StateGameOver || gameState == StateReview) && !gameSession.Over():
+		resultSeen = false
+		gameState = StateGameplay
+	}
+}
+
+// showGameOver shows the game over screen for a finished game
+func showGameOver() {
+
+	gameOverMenu = newMenu(resultMessage(), "New game")
+	if netGame == nil {
+		gameOverMenu.AddItem("Rematch")
+	}
+	gameOverMenu.AddItem("Save game")
+	gameOverMenu.AddItem("Review")
+	gameOverMenu.AddItem("Main menu")
+
+	gameState = StateGameOver
+}
+
+// rematch starts the same game again with players swapping their sides
+func rematch() {
+
+	s := gameSession
+
+	first := s.Turn
+	if len(s.History) != 0 {
+		first = s.History[0].Player
+	}
+
+	options := misc.SessionOptions{BoardSide: s.Board.CellsHoriz, WinLength: s.WinLength, Rules: s.Rules,
+		PlayerX: s.PlayerX, PlayerO: s.PlayerO, FirstMove: first, TimeControl: s.TimeControl}
+
+	// names stay with sides, kinds and engine settings are swapped
+	options.PlayerX.Kind, options.PlayerO.Kind = s.PlayerO.Kind, s.PlayerX.Kind
+	options.PlayerX.AI, options.PlayerO.AI = s.PlayerO.AI, s.PlayerX.AI
+
+	newGame(options)
+	gameState = StateGameplay
+}
+
+// gameOverKey handles keys of the game over screen
+func gameOverKey(ev termbox.Event) {
+
+	message = ""
+
+	done, cancelled := gameOverMenu.HandleKey(ev)
+	if !done {
+		return
+	}
+
+	if cancelled {
+		leaveGame()
+		return
+	}
+
+	switch gameOverMenu.Chosen() {
+	case "New game":
+		leaveGame()
+		showMenu(newGameMenu)
+	case "Rematch":
+		rematch()
+	case "Save game":
+		askFileName("Save game as", saveGame)
+	case "Review":
+		reviewMove = len(gameSession.History)
+		gameState = StateReview
+	case "Main menu":
+		leaveGame()
+	}
+}
+
+// reviewKey steps through moves of a finished game
+func reviewKey(ev termbox.Event) {
+
+	switch ev.Key {
+	case termbox.KeyArrowLeft, termbox.KeyArrowUp, termbox.KeyPgup:
+		if reviewMove > 0 {
+			reviewMove--
+		}
+	case termbox.KeyArrowRight, termbox.KeyArrowDown, termbox.KeyPgdn:
+		if reviewMove < len(gameSession.History) {
+			reviewMove++
+		}
+	case termbox.KeyHome:
+		reviewMove = 0
+	case termbox.KeyEnd:
+		reviewMove = len(gameSession.History)
+	case termbox.KeyF5:
+		moveNumbers = !moveNumbers
+	case termbox.KeyEsc, termbox.KeyEnter:
+		gameState = StateGameOver
+	}
+}
+
+// paintGameOver draws the game over screen over the board
+func paintGameOver() {
+
+	text := []string{fmt.Sprintf("Moves: %d", len(gameSession.History))}
+	if !gameEnded.IsZero() {
+		text = append(text, fmt.Sprintf("Duration: %v", gameEnded.Sub(gameStarted).Round(time.Second)))
+	}
+	if localSide() != misc.E {
+		text = append(text, "", score.String())
+	}
+
+	ui.DrawDialog(board.X+4, board.Y+2, gameOverMenu, text)
+}
+
+// paintReview draws position after reviewMove moves, the last of them is
+// marked by the cursor
+func paintReview() {
+
+	position := *board
+	position.BoardDescription = gameSession.Position(reviewMove)
+	position.History, position.ShowNumbers = gameSession.History[:reviewMove], moveNumbers
+
+	mark := cursor
+	mark.Board = &position
+	if reviewMove > 0 {
+		last := gameSession.History[reviewMove-1].Pos
+		mark.Col, mark.Row = last.Col, last.Row
+	}
+
+	intervals := []misc.Interval{}
+	if reviewMove == len(gameSession.History) {
+		intervals = gameSession.Intervals
+	}
+
+	ui.DrawBoard(&position, mark, intervals)
+
+	msgY := board.Y + board.GetHeight() + 3
+
+	ui.DrawText(board.X, msgY, theme.TextFg, theme.TextBg,
+		fmt.Sprintf("Move %d of %d", reviewMove, len(gameSession.History)))
+
+	drawSidePanel(msgY+2, reviewMove)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"github.com/nsf/termbox-go"
+	"github.com/risboo6909/goblin/misc"
+	"github.com/risboo6909/goblin/ui"
+)
+
+// heatmapResult is a heatmap made for a given session when it had a given number of moves
+type heatmapResult struct {
+	session *misc.Session
+	moves   int
+
+	heatmap misc.Heatmap
+	err     error
+}
+
+var (
+	// analysis overlay is shown
+	showHeatmap bool
+
+	// heatmaps are made in their own goroutine and sent here, closing
+	// stopHeatmap cancels the pending one
+	heatmapResults = make(chan heatmapResult, 1)
+	heatmapPending bool
+	stopHeatmap    chan struct{}
+
+	// the latest heatmap, it may be made for another position
+	heatmap *heatmapResult
+)
+
+// heatmapCurrent returns true if the latest heatmap is made for the current position
+func heatmapCurrent() bool {
+	return heatmap != nil && heatmap.session == gameSession && heatmap.moves == len(gameSession.History)
+}
+
+// updateHeatmap starts making a heatmap if the overlay is shown and the position
+// has changed, analysis waits while engine is to move as they share the engine
+// and the engine's clock is running
+func updateHeatmap() {
+
+	if !showHeatmap || heatmapPending || heatmapCurrent() || gameSession.Over() ||
+		thinking || gameSession.EngineToMove() {
+		return
+	}
+
+	heatmapPending = true
+	stopHeatmap = make(chan struct{})
+
+	session, moves, stop := gameSession, len(gameSession.History), stopHeatmap
+	position := gameSession.Copy()
+
+	go func() {
+		h, err := position.Heatmap(stop)
+		heatmapResults <- heatmapResult{session, moves, h, err}
+	}()
+}
+
+// cancelHeatmap stops making the pending heatmap, it is made again later
+func cancelHeatmap() {
+	if heatmapPending {
+		select {
+		case <-stopHeatmap:
+		default:
+			close(stopHeatmap)
+		}
+	}
+}
+
+// heatmapReady keeps a made heatmap, another one is started at once if the position has changed meanwhile
+func heatmapReady(result heatmapResult) {
+	heatmapPending = false
+	if result.err != misc.ErrStopped {
+		heatmap = &result
+	}
+	updateHeatmap()
+}
+
+// heatmapColor returns the color of a score within the heatmap range
+func heatmapColor(h misc.Heatmap, score float64) termbox.Attribute {
+
+	colors := theme.Heatmap
+
+	if h.Max <= h.Min {
+		return colors[0]
+	}
+
+	i := int((score - h.Min) / (h.Max - h.Min) * float64(len(colors)))
+	if i >= len(colors) {
+		i = len(colors) - 1
+	}
+
+	return colors[i]
+}
+
+// heatmapCellColors returns colors of a cell rated with a heatmap color, board
+// lines keep their color and text attributes of the color, like reverse, apply to them
+func heatmapCellColors(color termbox.Attribute) (fg, bg termbox.Attribute) {
+	return theme.Board.BoardColor | (color &^ ui.Plain(color)), ui.Plain(color)
+}
+
+// drawHeatmap colors free cells by their scores and draws the legend at x, y,
+// returns false if there is nothing to draw, the cursor cell is not colored
+func drawHeatmap(x, y int) bool {
+
+	if !showHeatmap || !heatmapCurrent() || heatmap.err != nil {
+		return false
+	}
+
+	h := heatmap.heatmap
+	marks := []ui.Mark{}
+
+	for _, idx := range gameSession.Board.GetFreeIndices() {
+		col, row, _ := gameSession.Board.FromLinear(idx)
+		// cursor stays visible
+		if col == cursor.Col && row == cursor.Row {
+			continue
+		}
+		fg, bg := heatmapCellColors(heatmapColor(h, h.Scores[idx]))
+		marks = append(marks, ui.Mark{Pos: misc.CellPosition{Col: col, Row: row}, FgColor: fg, BgColor: bg})
+	}
+
+	ui.DrawMarks(board, marks)
+
+	legend := string(h.Side) + " to move: low "
+	ui.DrawText(x, y, theme.TextFg, theme.TextBg, legend)
+	x += len(legend)
+
+	for _, color := range theme.Heatmap {
+		termbox.SetCell(x, y, ' ', color, ui.Plain(color))
+		termbox.SetCell(x+1, y, ' ', color, ui.Plain(color))
+		x += 2
+	}
+	ui.DrawText(x, y, theme.TextFg, theme.TextBg, " high")
+
+	return true
+}
+
+// toggleHeatmap shows or hides the analysis overlay
+func toggleHeatmap() {
+	showHeatmap = !showHeatmap
+	updateHeatmap()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nsf/termbox-go"
+	"github.com/risboo6909/goblin/ui"
+)
+
+func TestHeatmapCellColors(t *testing.T) {
+
+	defer func(saved ui.Theme) { theme = saved }(theme)
+
+	for _, tc := range []struct {
+		theme  string
+		color  termbox.Attribute
+		fg, bg termbox.Attribute
+	}{
+		// board lines stay black on every color of the dark theme
+		{"dark", termbox.ColorBlue, termbox.ColorBlack, termbox.ColorBlue},
+		{"dark", termbox.ColorGreen, termbox.ColorBlack, termbox.ColorGreen},
+		{"dark", termbox.ColorCyan, termbox.ColorBlack, termbox.ColorCyan},
+		{"dark", termbox.ColorRed, termbox.ColorBlack, termbox.ColorRed},
+		// attributes go to the foreground only
+		{"dark", termbox.ColorYellow | termbox.AttrBold, termbox.ColorBlack | termbox.AttrBold, termbox.ColorYellow},
+		{"monochrome", termbox.ColorDefault | termbox.AttrReverse, termbox.ColorDefault | termbox.AttrReverse, termbox.ColorDefault},
+		{"monochrome", termbox.ColorDefault, termbox.ColorDefault, termbox.ColorDefault},
+	} {
+		var err error
+		if theme, err = ui.FindTheme(tc.theme, nil); err != nil {
+			t.Fatal(err)
+		}
+		if fg, bg := heatmapCellColors(tc.color); fg != tc.fg || bg != tc.bg {
+			t.Errorf("%s theme, color %v: got %v, %v, expected %v, %v", tc.theme, tc.color, fg, bg, tc.fg, tc.bg)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/risboo6909/goblin/misc"
+	"github.com/risboo6909/goblin/ui"
+)
+
+// number of Monte-Carlo candidates shown with a hint
+const hintCandidates = 5
+
+// hintResult is a hint found for a given session when it had a given number of moves
+type hintResult struct {
+	session *misc.Session
+	moves   int
+
+	hint misc.Hint
+	err  error
+}
+
+var (
+	// hints are searched in their own goroutine and sent here
+	hintResults = make(chan hintResult, 1)
+
+	// hinting is true while a hint is searched, closing stopHint makes it ready at once
+	hinting  bool
+	stopHint chan struct{}
+
+	// the hint shown for the current position, nil if there is none
+	hint *hintResult
+)
+
+// askHint starts searching a hint for the local player if it is its turn
+func askHint() {
+
+	if hinting {
+		return
+	}
+
+	if netGame != nil && gameSession.Turn != netGame.Local ||
+		gameSession.Player(gameSession.Turn).Kind != misc.Human || gameSession.Over() {
+		message = "Hints are given on your turn only"
+		return
+	}
+
+	hinting, hint = true, nil
+	stopHint = make(chan struct{})
+
+	session, moves, stop := gameSession, len(gameSession.History), stopHint
+	position := gameSession.Copy()
+
+	go func() {
+		h, err := position.Hint(hintCandidates, stop)
+		hintResults <- hintResult{session, moves, h, err}
+	}()
+
+	message = "Looking for a hint..."
+}
+
+// cancelHint stops hint search and drops its result
+func cancelHint() {
+	if hinting {
+		close(stopHint)
+		hinting = false
+	}
+	hint = nil
+}
+
+// hintFound shows a hint if the game hasn't changed since it was asked for
+func hintFound(result hintResult) {
+
+	if result.session != gameSession || result.moves != len(gameSession.History) || !hinting {
+		return
+	}
+
+	hinting = false
+
+	if result.err != nil {
+		message = "No hint: " + result.err.Error()
+		return
+	}
+
+	hint = &result
+	message = "Hint: " + board.Notation.Format(result.hint.Best)
+}
+
+// currentHint returns the hint for the current position, a hint becomes
+// outdated once a move is made
+func currentHint() *misc.Hint {
+	if hint == nil || hint.session != gameSession || hint.moves != len(gameSession.History) {
+		hint = nil
+		return nil
+	}
+	return &hint.hint
+}
+
+// drawHint marks the suggested cell and numbers the other candidates
+func drawHint() {
+
+	h := currentHint()
+	if h == nil {
+		return
+	}
+
+	marks := []ui.Mark{}
+
+	for i, c := range h.Candidates {
+		if c.Pos != h.Best && i < 9 {
+			marks = append(marks, ui.Mark{Pos: c.Pos, Ch: rune('1' + i), FgColor: theme.CandidateFg, BgColor: theme.CandidateBg})
+		}
+	}
+
+	marks = append(marks, ui.Mark{Pos: h.Best, Ch: '?', FgColor: theme.HintFg,
+		BgColor: theme.HintBg})
+
+	ui.DrawMarks(board, marks)
+}
+
+// hintLines lists hint candidates with their scores for the side panel
+func hintLines() []string {
+
+	h := currentHint()
+	if h == nil {
+		return nil
+	}
+
+	lines := []string{"Hint: " + board.Notation.Format(h.Best)}
+	for i, c := range h.Candidates {
+		lines = append(lines, fmt.Sprintf("%3d. %-5s %.2f", i+1, board.Notation.Format(c.Pos), c.Score))
+	}
+
+	return lines
+}
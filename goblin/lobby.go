@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/risboo6909/goblin/lobby"
+	"github.com/risboo6909/goblin/misc"
+)
+
+// runLobby runs multi-user lobby server, it is started by "goblin lobby"
+func runLobby(args []string) {
+
+	flags := flag.NewFlagSet("lobby", flag.ExitOnError)
+
+	addr := flags.String("addr", ":2323", "address to listen on")
+	moveTime := flags.Duration("move-time", 2*time.Second, "time limit for computer moves")
+	depth := flags.Int("depth", misc.DefaultDepth, "search depth of computer players")
+
+	flags.Parse(args)
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "lobby is open on %s, connect with telnet\n", ln.Addr())
+
+	if err := lobby.New(*moveTime, *depth).Serve(ln); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"os"
 
 	"github.com/nsf/termbox-go"
@@ -10,105 +12,464 @@ import (
 )
 
 var (
-	gameState = StateGameplay
-	moveBoard = false
+	boardSide = flag.Int("size", 13, "number of cells along a board side")
+	winLength = flag.Int("win", 4, "number of stones in a row required to win")
+	playerX   = flag.String("x", "human", "who plays X: human or engine")
+	playerO   = flag.String("o", "engine", "who plays O: human or engine")
+	depthX    = flag.Int("depth-x", misc.DefaultDepth, "search depth of engine playing X")
+	depthO    = flag.Int("depth-o", misc.DefaultDepth, "search depth of engine playing O")
+	moveDelay = flag.Duration("delay", 0, "minimal delay before engine moves")
+	color     = flag.String("color", "", "play against engine for a given side: x or o, overrides -x and -o")
+	firstMove = flag.String("first", "x", "side which moves first: x or o")
+	rules     = flag.String("rules", misc.Freestyle.String(), "game rules: freestyle or pro")
+	logFile   = flag.String("log", "", "write engine log to a given file")
+	timeCtl   = flag.String("time", "", "time control: 5m for sudden death, 5m+3s with increment, 10s/move for fixed time per move")
+)
+
+var (
+	gameState = StateMainMenu
+
+	// stones are shown as their move numbers
+	moveNumbers = false
+
+	gameSession *misc.Session
 
-	gameSession = misc.CreateNewSession(13, 4, misc.X)
+	board  *ui.DrawableBoard
+	cursor ui.Cursor
 
-	board = ui.CloneExistingBoard(gameSession.Board, 0, 0, termbox.ColorBlack, termbox.ColorBlue,
-		termbox.ColorRed, termbox.ColorBlack)
+	lastMoveTime time.Time
 
-	cursor = ui.Cursor{Board: board, Col: 2, Row: 1,
-		FgColor: termbox.ColorGreen, BgColor: termbox.ColorWhite}
+	// active file name prompt and an action to perform with entered name
+	prompt       *ui.Prompt
+	promptAction func(string)
+
+	// message shown under the board
+	message  string
+	fileName = "goblin.json"
 )
 
-func update(ev termbox.Event) {
+// newPlayer creates a player from command line settings
+func newPlayer(kind, name string, depth int) (misc.Player, error) {
+	switch kind {
+	case "human":
+		return misc.HumanPlayer(name), nil
+	case "engine":
+		return misc.EnginePlayer(name, depth), nil
+	}
+	return misc.Player{}, fmt.Errorf("unknown player kind %q", kind)
+}
 
-	switch gameState {
+// parseSide converts command line side name into a cell value
+func parseSide(side string) (misc.Cell, error) {
+	switch side {
+	case "x", "X":
+		return misc.X, nil
+	case "o", "O":
+		return misc.O, nil
+	}
+	return misc.E, fmt.Errorf("unknown side %q", side)
+}
 
-	case StateGameplay:
+// sessionOptions builds new game options from command line flags
+func sessionOptions() (misc.SessionOptions, error) {
 
-		switch ev.Type {
+	options := misc.SessionOptions{BoardSide: *boardSide, WinLength: *winLength}
 
-		case termbox.EventKey:
-			if ev.Key == termbox.KeyEsc {
-				termbox.Close()
-				os.Exit(0)
-			}
+	if options.BoardSide < 3 || options.BoardSide > misc.MaxBoardSide {
+		return options, fmt.Errorf("board size must be from 3 to %d", misc.MaxBoardSide)
+	}
 
-			if ev.Key == termbox.KeyF10 {
-				moveBoard = !moveBoard
-			}
+	var err error
 
-			// cursor control
+	if options.Rules, err = misc.ParseRules(*rules); err != nil {
+		return options, err
+	}
 
-			if ev.Key == termbox.KeyArrowRight {
-				if moveBoard {
-					board.X++
-				} else {
-					cursor.MoveRight()
-				}
-			}
+	if *color != "" {
+		side, err := parseSide(*color)
+		if err != nil {
+			return options, err
+		}
+		*playerX, *playerO = "engine", "human"
+		if side == misc.X {
+			*playerX, *playerO = "human", "engine"
+		}
+	}
 
-			if ev.Key == termbox.KeyArrowLeft {
-				if moveBoard {
-					board.X--
-				} else {
-					cursor.MoveLeft()
-				}
-			}
+	if options.PlayerX, err = newPlayer(*playerX, "Player X", *depthX); err != nil {
+		return options, err
+	}
 
-			if ev.Key == termbox.KeyArrowUp {
-				if moveBoard {
-					board.Y--
-				} else {
-					cursor.MoveUp()
-				}
-			}
+	if options.PlayerO, err = newPlayer(*playerO, "Player O", *depthO); err != nil {
+		return options, err
+	}
 
-			if ev.Key == termbox.KeyArrowDown {
-				if moveBoard {
-					board.Y++
-				} else {
-					cursor.MoveDown()
-				}
-			}
+	if options.TimeControl, err = misc.ParseTimeControl(*timeCtl); err != nil {
+		return options, err
+	}
+
+	options.FirstMove, err = parseSide(*firstMove)
+
+	return options, err
+}
+
+// newGame starts a new game session
+func newGame(options misc.SessionOptions) {
+	setSession(misc.NewSession(options))
+}
+
+// setSession makes session current and sets up board and cursor for it
+func setSession(session *misc.Session) {
+
+	abortThinking()
+	cancelHint()
+	lastSearch = nil
+
+	gameSession = session
+
+	board = ui.CloneExistingBoard(gameSession.Board, 0, 0, theme.Board.BoardColor, theme.Board.BoardBg,
+		theme.Board.LabelsColor, theme.Board.LabelsBg)
+	board.BoardAttrs = theme.Board
+
+	cursor = ui.Cursor{Board: board, Col: board.CellsHoriz / 2, Row: board.CellsVert / 2,
+		FgColor: theme.CursorFg, BgColor: theme.CursorBg}
+
+	lastMoveTime = time.Now()
+
+	gameStarted, gameEnded = time.Now(), time.Time{}
+	resultSeen, resultCounted = false, session.Over()
+}
 
-			if (ev.Key == termbox.KeySpace || ev.Key == termbox.KeyEnter) && !moveBoard {
-				// can't modify occupied cell
-				if board.GetCell(cursor.Col, cursor.Row) == misc.E {
-					board.SetCell(cursor.Col, cursor.Row, misc.X)
-					gameSession.MakeMove()
-				}
+// askFileName shows file name prompt and calls action with entered name
+func askFileName(title string, action func(string)) {
+	prompt = ui.NewPrompt(title, fileName, theme.TextFg, theme.TextBg)
+	promptAction = func(name string) {
+		fileName = name
+		action(name)
+	}
+}
+
+func saveGame(name string) {
+	if err := gameSession.SaveToFile(name); err != nil {
+		message = "Can't save game: " + err.Error()
+	} else {
+		message = "Game saved to " + name
+	}
+}
+
+func loadGame(name string) {
+	session, err := misc.LoadFromFile(name)
+	if err != nil {
+		message = "Can't load game: " + err.Error()
+		return
+	}
+	setSession(session)
+	gameState = StateGameplay
+	message = "Game loaded from " + name
+}
+
+// engineTurn starts engine search if it is its turn and move delay has passed,
+// the game is lost by the side to move if its time is up
+func engineTurn() {
+	if gameSession.CheckTime() {
+		abortThinking()
+		return
+	}
+	if !thinking && gameSession.EngineToMove() && time.Since(lastMoveTime) >= *moveDelay {
+		startThinking()
+	}
+}
+
+// leaveGame ends the current game and returns to the main menu
+func leaveGame() {
+	abortThinking()
+	cancelHint()
+	if netGame != nil {
+		netGame.Close()
+		netGame, netMessages = nil, nil
+	}
+	showMenu(mainMenu)
+}
+
+// gameplayKey handles keys of the game screen
+func gameplayKey(ev termbox.Event) {
+
+	message = ""
+
+	if thinking {
+		thinkingKey(ev)
+		return
+	}
+
+	if netKey(ev) {
+		return
+	}
+
+	if ev.Key == termbox.KeyEsc {
+		leaveGame()
+		return
+	}
+
+	if ev.Key == termbox.KeyF5 {
+		moveNumbers = !moveNumbers
+	}
+
+	if ev.Ch == 'h' {
+		askHint()
+	}
+
+	if ev.Ch == 'a' {
+		toggleHeatmap()
+	}
+
+	if ev.Key == termbox.KeyF2 {
+		askFileName("Save game as", saveGame)
+	}
+
+	if ev.Key == termbox.KeyF3 {
+		askFileName("Load game from", loadGame)
+	}
+
+	// cursor control
+
+	if ev.Key == termbox.KeyArrowRight {
+		cursor.MoveRight()
+	}
+
+	if ev.Key == termbox.KeyArrowLeft {
+		cursor.MoveLeft()
+	}
+
+	if ev.Key == termbox.KeyArrowUp {
+		cursor.MoveUp()
+	}
+
+	if ev.Key == termbox.KeyArrowDown {
+		cursor.MoveDown()
+	}
+
+	if ev.Key == termbox.KeySpace || ev.Key == termbox.KeyEnter {
+		playAtCursor()
+	}
+}
+
+// playAtCursor makes a move of the local player at the cursor
+func playAtCursor() {
+
+	pos := misc.CellPosition{Col: cursor.Col, Row: cursor.Row}
+
+	cancelHint()
+
+	if netGame != nil {
+		if err := netGame.Play(pos); err != nil {
+			message = err.Error()
+		}
+		return
+	}
+
+	// session rejects moves to occupied cells, out of turn or after the game is over,
+	// engine starts thinking on the next paint tick
+	if err := gameSession.Play(pos); err == nil {
+		lastMoveTime = time.Now()
+	}
+}
+
+func update(ev termbox.Event) {
+
+	// board is panned in any screen showing it, moves are made only in game
+	if ev.Type == termbox.EventMouse && prompt == nil &&
+		(gameState == StateGameplay || gameState == StateGameOver || gameState == StateReview) {
+		mouseEvent(ev)
+		return
+	}
+
+	if ev.Type != termbox.EventKey {
+		return
+	}
+
+	// file name prompt takes all the input while it is shown
+	if prompt != nil {
+		if done, cancelled := prompt.HandleKey(ev); done {
+			action, name := promptAction, prompt.Value()
+			prompt, promptAction = nil, nil
+			if !cancelled {
+				action(name)
 			}
 		}
+		return
+	}
+
+	switch gameState {
+
+	case StateMainMenu:
+		menuKey(ev)
+
+	case StateHelp:
+		// any key returns to menu
+		showMenu(mainMenu)
+
+	case StateGameplay:
+		gameplayKey(ev)
 
+	case StateGameOver:
+		gameOverKey(ev)
+
+	case StateReview:
+		reviewKey(ev)
 	}
 
 }
 
+// resultMessage describes how the current game has ended
+func resultMessage() string {
+	winner := gameSession.Player(gameSession.Winner).Name
+	loser := gameSession.Player(misc.Opponent(gameSession.Winner)).Name
+	switch gameSession.Status {
+	case misc.GameWon:
+		return winner + " wins"
+	case misc.GameResigned:
+		return loser + " has resigned, " + winner + " wins"
+	case misc.GameTimeout:
+		return loser + " has run out of time, " + winner + " wins"
+	case misc.GameDraw:
+		return "Draw"
+	}
+	return ""
+}
+
 func paint() {
 
-	termbox.Clear(termbox.ColorBlack, termbox.ColorBlack)
+	termbox.Clear(theme.TextBg, theme.TextBg)
+
+	// game over screen is shown as soon as the game ends
+	if gameSession != nil {
+		checkGameOver()
+	}
 
 	switch gameState {
 
 	case StateGameplay:
-		ui.DrawBoard(board, cursor, gameSession.Intervals)
+		paintGame()
+
+	case StateGameOver:
+		paintGame()
+		paintGameOver()
+
+	case StateReview:
+		paintReview()
+
+	case StateMainMenu:
+		paintMenu()
+
+	case StateHelp:
+		paintHelp()
 	}
 
+	drawStatusBar()
+
 	termbox.Flush()
 }
 
+// paintGame draws the board with clocks and a prompt or a message under it
+func paintGame() {
+
+	board.History, board.ShowNumbers = gameSession.History, moveNumbers
+	ui.DrawBoard(board, cursor, gameSession.Intervals)
+
+	msgY := board.Y + board.GetHeight() + 3
+
+	updateHeatmap()
+	if drawHeatmap(board.X, msgY) {
+		msgY++
+	}
+
+	drawHint()
+
+	if gameSession.TimeControl.Timed() {
+		drawClocks(board.X, msgY)
+		msgY++
+	}
+
+	if thinking {
+		ui.DrawText(board.X, msgY, theme.AccentFg, theme.TextBg, thinkingStatus())
+		msgY++
+	}
+
+	if prompt != nil {
+		ui.DrawPrompt(board.X, msgY, prompt)
+	} else {
+		termbox.HideCursor()
+		ui.DrawText(board.X, msgY, theme.TextFg, theme.TextBg, message)
+	}
+
+	drawSidePanel(msgY+2, len(gameSession.History))
+}
+
+// drawClocks draws clocks of both players in a line starting at x, y
+func drawClocks(x, y int) {
+	for _, side := range []misc.Cell{misc.X, misc.O} {
+		running := gameSession.Clock(side).Running()
+		name := fmt.Sprintf("%c %s", side, gameSession.Player(side).Name)
+		x += ui.DrawClock(x, y, theme.TextFg, theme.TextBg, name, gameSession.TimeLeft(side), running) + 2
+	}
+}
+
 func main() {
 
-	err := termbox.Init()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			serve(os.Args[2:])
+			return
+		case "lobby":
+			runLobby(os.Args[2:])
+			return
+		}
+	}
+
+	flag.Parse()
+
+	options, err := sessionOptions()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if err := loadThemes(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		misc.SetLogOutput(f)
+	}
+
+	if *hostAddr != "" || *joinAddr != "" {
+		if err := startNetGame(options); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		gameState = StateGameplay
+	}
+
+	initMenus()
+
+	err = termbox.Init()
 	if err != nil {
 		panic(err)
 	}
 
 	defer termbox.Close()
 
+	setOutputMode()
+	termbox.SetInputMode(termbox.InputEsc | termbox.InputMouse)
+
 	eventQ := make(chan termbox.Event)
 	go func() {
 		for {
@@ -123,7 +484,22 @@ func main() {
 		case ev := <-eventQ:
 			update(ev)
 			paint()
+		case result := <-engineResults:
+			engineMoved(result)
+			paint()
+		case result := <-heatmapResults:
+			heatmapReady(result)
+			paint()
+		case result := <-hintResults:
+			hintFound(result)
+			paint()
+		case msg := <-netMessages:
+			handleNetMessage(msg)
+			paint()
 		case <-paintTick.C:
+			if gameState == StateGameplay {
+				engineTurn()
+			}
 			paint()
 		}
 	}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/risboo6909/goblin/misc"
+	"github.com/risboo6909/goblin/ui"
+)
+
+// engine depths of difficulty levels offered by the new game menu
+var difficulties = []struct {
+	name  string
+	depth int
+}{{"easy", 1}, {"medium", 3}, {"hard", misc.DefaultDepth}}
+
+var (
+	mainMenu     *ui.Menu
+	newGameMenu  *ui.Menu
+	settingsMenu *ui.Menu
+
+	// menu shown in StateMainMenu
+	menu *ui.Menu
+)
+
+var helpText = []string{
+	"Goblin is a game of getting a given number of stones in a row.",
+	"",
+	"Arrows      move cursor",
+	"Space/Enter make a move, or click a cell",
+	"Mouse drag  move board",
+	"F2          save game",
+	"F3          load game",
+	"F5          show move numbers",
+	"h           ask engine for a hint",
+	"a           show analysis heatmap",
+	"Esc         return to menu",
+	"m           make engine move at once",
+	"",
+	"In a network game:",
+	"F4          ask to take back a move",
+	"F6          resign",
+	"y/n         answer a take back request",
+	"c           chat",
+	"",
+	"Press any key to return to menu",
+}
+
+func newMenu(title string, items ...string) *ui.Menu {
+	return ui.NewMenu(title, theme.TextFg, theme.TextBg, theme.SelectedFg, theme.SelectedBg, items...)
+}
+
+// selectValue chooses a value of an option, the value is added if the option lacks it
+func selectValue(item *ui.MenuItem, value string) {
+	if !item.Select(value) {
+		item.Values = append(item.Values, value)
+		item.Select(value)
+	}
+}
+
+// initMenus creates menus, options are preset from command line flags
+func initMenus() {
+
+	mainMenu = newMenu("Goblin", "New game", "Load game", "Settings", "Help", "Quit")
+
+	newGameMenu = newMenu("New game")
+	newGameMenu.AddOption("Board size", "7", "9", "11", "13", "15", "19")
+	newGameMenu.AddOption("Win length", "3", "4", "5", "6")
+	newGameMenu.AddOption("Rules", misc.Freestyle.String(), misc.Pro.String())
+	newGameMenu.AddOption("Play as", "X", "O", "both", "nobody")
+	newGameMenu.AddOption("Difficulty")
+	newGameMenu.AddItem("Start game")
+	newGameMenu.AddItem("Back")
+
+	selectValue(newGameMenu.Item("Board size"), strconv.Itoa(*boardSide))
+	selectValue(newGameMenu.Item("Win length"), strconv.Itoa(*winLength))
+	selectValue(newGameMenu.Item("Rules"), *rules)
+
+	playAs := "both"
+	switch {
+	case *color != "":
+		side, _ := parseSide(*color)
+		playAs = string(side)
+	case *playerX == "human" && *playerO == "engine":
+		playAs = "X"
+	case *playerX == "engine" && *playerO == "human":
+		playAs = "O"
+	case *playerX == "engine" && *playerO == "engine":
+		playAs = "nobody"
+	}
+	selectValue(newGameMenu.Item("Play as"), playAs)
+
+	// the strongest level not deeper than the engine's depth
+	depth := *depthO
+	if playAs == "O" {
+		depth = *depthX
+	}
+	difficulty := newGameMenu.Item("Difficulty")
+	for i, d := range difficulties {
+		difficulty.Values = append(difficulty.Values, d.name)
+		if d.depth <= depth {
+			difficulty.Selected = i
+		}
+	}
+
+	settingsMenu = newMenu("Settings")
+	settingsMenu.AddOption("First move", "X", "O")
+	settingsMenu.AddOption("Time control", "none", "1m", "5m", "5m+3s", "10s/move")
+	settingsMenu.AddOption("Engine delay", "0s", "500ms", "1s", "2s")
+	settingsMenu.AddOption("Theme", themeNames()...)
+	settingsMenu.AddItem("Back")
+
+	first, _ := parseSide(*firstMove)
+	selectValue(settingsMenu.Item("First move"), string(first))
+
+	timeControl := "none"
+	if tc, err := misc.ParseTimeControl(*timeCtl); err == nil && tc.Timed() {
+		timeControl = *timeCtl
+	}
+	selectValue(settingsMenu.Item("Time control"), timeControl)
+	selectValue(settingsMenu.Item("Engine delay"), moveDelay.String())
+	selectValue(settingsMenu.Item("Theme"), *themeName)
+
+	menu = mainMenu
+}
+
+// showMenu switches to the menu screen
+func showMenu(m *ui.Menu) {
+	gameState = StateMainMenu
+	menu = m
+}
+
+// applyMenuOptions sets command line settings from the new game and settings menus
+func applyMenuOptions() {
+
+	*boardSide, _ = strconv.Atoi(newGameMenu.Item("Board size").Value())
+	*winLength, _ = strconv.Atoi(newGameMenu.Item("Win length").Value())
+	*rules = newGameMenu.Item("Rules").Value()
+
+	*color = ""
+	switch newGameMenu.Item("Play as").Value() {
+	case "X", "O":
+		*color = newGameMenu.Item("Play as").Value()
+	case "both":
+		*playerX, *playerO = "human", "human"
+	case "nobody":
+		*playerX, *playerO = "engine", "engine"
+	}
+
+	depth := difficulties[newGameMenu.Item("Difficulty").Selected].depth
+	*depthX, *depthO = depth, depth
+
+	*firstMove = settingsMenu.Item("First move").Value()
+	*timeCtl = settingsMenu.Item("Time control").Value()
+	*moveDelay, _ = time.ParseDuration(settingsMenu.Item("Engine delay").Value())
+}
+
+// startGame starts a game with settings chosen in menus
+func startGame() {
+
+	applyMenuOptions()
+
+	options, err := sessionOptions()
+	if err != nil {
+		message = err.Error()
+		return
+	}
+
+	if options.WinLength > options.BoardSide {
+		message = "Win length can't be longer than board side"
+		return
+	}
+
+	newGame(options)
+	gameState = StateGameplay
+}
+
+// quit closes terminal and exits
+func quit() {
+	termbox.Close()
+	os.Exit(0)
+}
+
+// menuKey handles keys of menu screens
+func menuKey(ev termbox.Event) {
+
+	message = ""
+
+	done, cancelled := menu.HandleKey(ev)
+
+	// theme is changed at once to show how it looks
+	if menu == settingsMenu {
+		setTheme(settingsMenu.Item("Theme").Value())
+	}
+
+	if !done {
+		return
+	}
+
+	if cancelled {
+		if menu == mainMenu {
+			quit()
+		}
+		showMenu(mainMenu)
+		return
+	}
+
+	switch menu.Chosen() {
+	case "New game":
+		showMenu(newGameMenu)
+	case "Load game":
+		askFileName("Load game from", loadGame)
+	case "Settings":
+		showMenu(settingsMenu)
+	case "Help":
+		gameState = StateHelp
+	case "Quit":
+		quit()
+	case "Start game":
+		startGame()
+	case "Back":
+		showMenu(mainMenu)
+	}
+}
+
+// paintMenu draws the current menu with a prompt or a message under it
+func paintMenu() {
+
+	x, y := 4, 2
+
+	ui.DrawMenu(x, y, menu)
+
+	msgY := y + len(menu.Items) + 3
+
+	if prompt != nil {
+		ui.DrawPrompt(x, msgY, prompt)
+	} else {
+		termbox.HideCursor()
+		ui.DrawText(x, msgY, theme.TextFg, theme.TextBg, message)
+	}
+}
+
+// paintHelp draws the help screen
+func paintHelp() {
+	for i, line := range helpText {
+		ui.DrawText(4, 2+i, theme.TextFg, theme.TextBg, line)
+	}
+}
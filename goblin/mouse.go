@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/nsf/termbox-go"
+	"github.com/risboo6909/goblin/ui"
+)
+
+var (
+	// left button is held down, dragX and dragY are where the mouse was last seen
+	dragging     bool
+	dragX, dragY int
+
+	// true if the mouse has moved since the button was pressed
+	dragged bool
+)
+
+// mouseEvent pans the board when it is dragged and makes a move at a clicked
+// cell, clicks are taken when the button is released without moving
+func mouseEvent(ev termbox.Event) {
+
+	switch ev.Key {
+
+	case termbox.MouseLeft:
+		if dragging && ev.Mod&termbox.ModMotion != 0 {
+			board.X += ev.MouseX - dragX
+			board.Y += ev.MouseY - dragY
+			dragX, dragY, dragged = ev.MouseX, ev.MouseY, true
+			return
+		}
+		dragging, dragged = true, false
+		dragX, dragY = ev.MouseX, ev.MouseY
+
+	case termbox.MouseRelease:
+		if dragging && !dragged && gameState == StateGameplay {
+			click(ev.MouseX, ev.MouseY)
+		}
+		dragging = false
+	}
+}
+
+// click moves the cursor to a clicked cell and plays there
+func click(x, y int) {
+
+	col, row, ok := ui.ScreenToCell(board, x, y)
+	if !ok {
+		return
+	}
+
+	message = ""
+
+	if thinking {
+		message = "Engine is thinking, press m to move now or Esc to abort the game"
+		return
+	}
+
+	cursor.Col, cursor.Row = col, row
+	playAtCursor()
+}
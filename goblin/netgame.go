@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+	"github.com/risboo6909/goblin/misc"
+	"github.com/risboo6909/goblin/netplay"
+	"github.com/risboo6909/goblin/ui"
+)
+
+var (
+	hostAddr   = flag.String("host", "", "host a network game on a given address, like :7777, your side is set by -color")
+	joinAddr   = flag.String("join", "", "join a network game hosted on a given address")
+	playerName = flag.String("name", os.Getenv("USER"), "your name in a network game")
+)
+
+var (
+	netGame *netplay.Game
+
+	// messages of a network game, nil if the game is local
+	netMessages chan netplay.Message
+)
+
+// askRules shows rules offered by the host and asks whether to accept them
+func askRules(offer netplay.RulesOffer) bool {
+	fmt.Printf("Host offers %v\nAccept? [Y/n] ", offer)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "" || answer == "y" || answer == "yes"
+}
+
+// startNetGame hosts or joins a network game, board and the first move
+// are taken from options, host plays the side set by -color, X by default
+func startNetGame(options misc.SessionOptions) error {
+
+	name := *playerName
+	if name == "" {
+		name = "Player"
+	}
+
+	var err error
+
+	if *hostAddr != "" {
+
+		side := misc.Cell(misc.X)
+		if *color != "" {
+			if side, err = parseSide(*color); err != nil {
+				return err
+			}
+		}
+
+		offer := netplay.RulesOffer{BoardSide: options.BoardSide, WinLength: options.WinLength,
+			Rules: options.Rules.String(), FirstMove: string(options.FirstMove), HostSide: string(side)}
+
+		ln, err := net.Listen("tcp", *hostAddr)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Waiting for opponent on %s\n", ln.Addr())
+
+		if netGame, err = netplay.Host(ln, offer, name); err != nil {
+			ln.Close()
+			return err
+		}
+
+	} else if netGame, err = netplay.Join(*joinAddr, name, askRules); err != nil {
+		return err
+	}
+
+	netMessages = netGame.Messages
+
+	setSession(netGame.Session)
+	message = fmt.Sprintf("Playing %c against %s", netGame.Local, netGame.PeerName)
+
+	return nil
+}
+
+// handleNetMessage applies a message of a network game
+func handleNetMessage(msg netplay.Message) {
+
+	notice := netGame.Handle(msg)
+
+	// session is replaced when the game is restored after reconnection
+	if netGame.Session != gameSession {
+		x, y := board.X, board.Y
+		setSession(netGame.Session)
+		board.X, board.Y = x, y
+	}
+
+	if notice != "" {
+		message = notice
+	}
+}
+
+// netKey handles keys specific to a network game, returns false for keys
+// handled as usual
+func netKey(ev termbox.Event) bool {
+
+	if netGame == nil {
+		return false
+	}
+
+	var err error
+
+	switch {
+
+	case ev.Key == termbox.KeyEsc:
+		netGame.Close()
+		return false
+
+	case ev.Key == termbox.KeyF3:
+		message = "Games can't be loaded while playing over network"
+
+	case ev.Key == termbox.KeyF4:
+		if err = netGame.RequestUndo(); err == nil {
+			message = "Asked " + netGame.PeerName + " to take back the last move"
+		}
+
+	case ev.Key == termbox.KeyF6:
+		err = netGame.Resign()
+
+	case netGame.UndoRequested && (ev.Ch == 'y' || ev.Ch == 'n'):
+		err = netGame.AnswerUndo(ev.Ch == 'y')
+
+	case ev.Ch == 'c':
+		prompt = ui.NewPrompt("Say", "", theme.TextFg, theme.TextBg)
+		promptAction = func(text string) {
+			if err := netGame.Chat(text); err != nil {
+				message = err.Error()
+			}
+		}
+
+	default:
+		return false
+	}
+
+	if err != nil {
+		message = err.Error()
+	}
+
+	return true
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/nsf/termbox-go"
+	"github.com/risboo6909/goblin/misc"
+	"github.com/risboo6909/goblin/ui"
+)
+
+// width of the side panel and the least height it is drawn with
+const (
+	panelWidth     = 30
+	panelMinHeight = 8
+)
+
+// the final search state of the latest engine move, nil if engine hasn't moved yet
+var lastSearch *misc.SearchInfo
+
+// describePlayer returns a panel line with player's side, name and kind
+func describePlayer(side misc.Cell) ui.PanelLine {
+
+	player := gameSession.Player(side)
+
+	kind := player.Kind.String()
+	switch {
+	case netGame != nil && side == netGame.Local:
+		kind = "you"
+	case netGame != nil:
+		kind = "remote"
+	case player.Kind == misc.Engine:
+		kind = fmt.Sprintf("engine, depth %d", player.AI.MaxDepth())
+	}
+
+	fg := theme.TextFg
+	if !gameSession.Over() && gameSession.Turn == side {
+		fg |= termbox.AttrBold
+	}
+
+	return ui.PanelLine{Text: fmt.Sprintf("%c %s (%s)", side, player.Name, kind), FgColor: fg, BgColor: theme.TextBg}
+}
+
+// moveList formats the first moves of the game in two columns, X moves go to the left
+func moveList(moves int) []string {
+
+	history := gameSession.History[:moves]
+	lines := []string{}
+
+	for i := 0; i < len(history); i++ {
+
+		x, o := "", ""
+
+		if history[i].Player == misc.X {
+			x = board.Notation.Format(history[i].Pos)
+			if i+1 < len(history) && history[i+1].Player == misc.O {
+				i++
+				o = board.Notation.Format(history[i].Pos)
+			}
+		} else {
+			x, o = "...", board.Notation.Format(history[i].Pos)
+		}
+
+		lines = append(lines, fmt.Sprintf("%3d. %-5s %s", len(lines)+1, x, o))
+	}
+
+	return lines
+}
+
+// sidePanelLines describes the game and lists its first moves, the list is
+// cut from its start to fit height lines
+func sidePanelLines(moves, height int) []ui.PanelLine {
+
+	line := func(format string, args ...interface{}) ui.PanelLine {
+		return ui.PanelLine{Text: fmt.Sprintf(format, args...), FgColor: theme.TextFg, BgColor: theme.TextBg}
+	}
+
+	s := gameSession
+
+	lines := []ui.PanelLine{
+		describePlayer(misc.X),
+		describePlayer(misc.O),
+		line(""),
+		line("Board %dx%d, %d in a row", s.Board.CellsHoriz, s.Board.CellsVert, s.WinLength),
+		line("Rules: %s", s.Rules),
+	}
+
+	if s.Over() {
+		lines = append(lines, line("%s", resultMessage()))
+	} else {
+		lines = append(lines, line("To move: %c %s", s.Turn, s.Player(s.Turn).Name))
+	}
+
+	if lastSearch != nil {
+		lines = append(lines, line("Engine: depth %d, score %d", lastSearch.Depth, lastSearch.Score))
+	}
+
+	if hints := hintLines(); hints != nil {
+		lines = append(lines, line(""))
+		for _, h := range hints {
+			lines = append(lines, line("%s", h))
+		}
+	}
+
+	list := moveList(moves)
+	if len(list) == 0 {
+		return lines
+	}
+
+	lines = append(lines, line(""), line("Moves:"))
+
+	if free := height - len(lines); free < len(list) {
+		if free < 0 {
+			free = 0
+		}
+		list = list[len(list)-free:]
+	}
+
+	for _, move := range list {
+		lines = append(lines, line("%s", move))
+	}
+
+	return lines
+}
+
+// drawSidePanel draws the panel to the right of the board if the terminal is
+// wide enough or under the board starting at belowY otherwise
+func drawSidePanel(belowY, moves int) {
+
+	width, height := termbox.Size()
+
+	// the last line is taken by the status bar
+	height--
+
+	x, y := board.X+board.GetWidth()+6, board.Y
+
+	if x+panelWidth > width {
+		x, y = board.X, belowY
+	}
+
+	panelHeight := height - y
+	panelW := panelWidth
+	if x+panelW > width {
+		panelW = width - x
+	}
+
+	if panelHeight < panelMinHeight || panelW < panelWidth/2 {
+		return
+	}
+
+	ui.DrawPanel(x, y, panelW, panelHeight, "Game", theme.TextFg, theme.TextBg,
+		sidePanelLines(moves, panelHeight-2))
+}
+
+// statusHints returns key hints and the name of the current mode
+func statusHints() (hints, mode string) {
+
+	if prompt != nil {
+		return "Enter confirm  Esc cancel", "INPUT"
+	}
+
+	switch gameState {
+
+	case StateMainMenu:
+		return "Up/Down choose  Left/Right change  Enter select  Esc back", "MENU"
+
+	case StateHelp:
+		return "Any key returns to menu", "HELP"
+
+	case StateGameOver:
+		return "Up/Down choose  Enter select  Esc menu", "GAME OVER"
+
+	case StateReview:
+		return "Arrows step  Home/End first/last move  F5 numbers  Esc back", "REVIEW"
+	}
+
+	switch {
+	case thinking:
+		return "m move now  Esc abort game", "THINKING"
+	case netGame != nil:
+		return "Arrows cursor  Space/click play  F4 undo  F6 resign  c chat  Esc leave", "NETWORK"
+	}
+
+	if hinting {
+		return "Arrows cursor  Space/click play  Esc menu", "HINT"
+	}
+
+	return "Arrows cursor  Space/click play  h hint  a analysis  F2 save  F3 load  F5 numbers  Esc menu", "PLAY"
+}
+
+// drawStatusBar draws key hints and the current mode on the last terminal line
+func drawStatusBar() {
+	width, height := termbox.Size()
+	hints, mode := statusHints()
+	ui.DrawStatusBar(0, height-1, width, theme.SelectedFg, theme.SelectedBg, " "+hints, " "+mode+" ")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/risboo6909/goblin/misc"
+	"github.com/risboo6909/goblin/server"
+)
+
+// serve runs HTTP API server, it is started by "goblin serve"
+func serve(args []string) {
+
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+
+	addr := flags.String("addr", ":8080", "address to listen on")
+	idle := flags.Duration("idle", 30*time.Minute, "remove sessions idle for longer than this")
+	maxSessions := flags.Int("max-sessions", 1000, "maximal number of sessions, zero means no limit")
+	moveTime := flags.Duration("move-time", 2*time.Second, "time limit for engine moves")
+	logFile := flags.String("log", "", "write engine log to a given file")
+
+	flags.Parse(args)
+
+	if *logFile != "" {
+		f, err := os.OpenFile(*logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		misc.SetLogOutput(f)
+	}
+
+	manager := misc.NewManager(*idle)
+	manager.MaxSessions = *maxSessions
+
+	defer manager.ExpireEvery(time.Minute)()
+
+	fmt.Fprintf(os.Stderr, "listening on %s\n", *addr)
+
+	if err := http.ListenAndServe(*addr, server.New(manager, *moveTime)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
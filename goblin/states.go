@@ -5,3 +5,12 @@ const StateMainMenu = 0
 
 // StateGameplay indicates that we are currently in gameplay
 const StateGameplay = 1
+
+// StateHelp indicates that we are currently viewing help
+const StateHelp = 2
+
+// StateGameOver indicates that we are currently looking at the result of a game
+const StateGameOver = 3
+
+// StateReview indicates that we are currently stepping through moves of a finished game
+const StateReview = 4
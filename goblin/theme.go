@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/nsf/termbox-go"
+	"github.com/risboo6909/goblin/ui"
+)
+
+var (
+	themeName  = flag.String("theme", ui.Themes[0].Name, "color theme: dark, light, high-contrast, monochrome or a theme from the themes file")
+	themesFile = flag.String("themes", "", "file with custom color themes, goblin/themes.json in the user config directory is read if not set")
+	colors256  = flag.Bool("256", false, "use 256-color output mode, needed by themes with palette colors")
+)
+
+var (
+	// colors of all screen elements
+	theme = ui.Themes[0]
+
+	// themes loaded from the themes file
+	customThemes []ui.Theme
+)
+
+// defaultThemesFile returns the themes file in the user config directory, the
+// file is optional, so an empty name is returned if it doesn't exist
+func defaultThemesFile() string {
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	name := filepath.Join(dir, "goblin", "themes.json")
+	if _, err := os.Stat(name); err != nil {
+		return ""
+	}
+
+	return name
+}
+
+// loadThemes reads custom themes and chooses the theme set by command line flags
+func loadThemes() error {
+
+	name := *themesFile
+	if name == "" {
+		name = defaultThemesFile()
+	}
+
+	if name != "" {
+		var err error
+		if customThemes, err = ui.LoadThemesFromFile(name); err != nil {
+			return err
+		}
+	}
+
+	return setTheme(*themeName)
+}
+
+// themeNames lists names of built-in and custom themes
+func themeNames() []string {
+	names, seen := []string{}, map[string]bool{}
+	for _, list := range [][]ui.Theme{ui.Themes, customThemes} {
+		for _, t := range list {
+			if !seen[t.Name] {
+				names, seen[t.Name] = append(names, t.Name), true
+			}
+		}
+	}
+	return names
+}
+
+// setTheme makes a theme current and recolors the board, the cursor and menus
+func setTheme(name string) error {
+
+	t, err := ui.FindTheme(name, customThemes)
+	if err != nil {
+		return err
+	}
+
+	theme, *themeName = t, name
+
+	if board != nil {
+		board.BoardAttrs = theme.Board
+		cursor.FgColor, cursor.BgColor = theme.CursorFg, theme.CursorBg
+	}
+
+	for _, m := range []*ui.Menu{mainMenu, newGameMenu, settingsMenu, gameOverMenu} {
+		if m != nil {
+			m.FgColor, m.BgColor, m.CurrentFg, m.CurrentBg = theme.TextFg, theme.TextBg, theme.SelectedFg, theme.SelectedBg
+		}
+	}
+
+	return nil
+}
+
+// setOutputMode switches terminal to 256 colors if asked by the command line
+func setOutputMode() {
+	if *colors256 {
+		termbox.SetOutputMode(termbox.Output256)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nsf/termbox-go"
+	"github.com/risboo6909/goblin/misc"
+)
+
+// engineResult is a move found by engine for a given session when it had a given number of moves
+type engineResult struct {
+	session *misc.Session
+	moves   int
+
+	pos misc.CellPosition
+	err error
+}
+
+var (
+	// engine searches in its own goroutine and sends the move here
+	engineResults = make(chan engineResult, 1)
+
+	// thinking is true while engine searches, closing stopThinking makes it move at once
+	thinking      bool
+	thinkingSince time.Time
+	stopThinking  chan struct{}
+
+	// the latest search state reported by engine
+	searchMu   sync.Mutex
+	searchInfo *misc.SearchInfo
+)
+
+// startThinking starts engine search on a copy of the current session, so
+// that the game screen is still drawn while engine thinks
+func startThinking() {
+
+	thinking, thinkingSince = true, time.Now()
+	stopThinking = make(chan struct{})
+
+	// analysis would hold the engine while the clock is running
+	cancelHeatmap()
+
+	searchMu.Lock()
+	searchInfo = nil
+	searchMu.Unlock()
+
+	session, moves, stop := gameSession, len(gameSession.History), stopThinking
+	position := gameSession.Copy()
+
+	go func() {
+		pos, err := position.EngineMove(func(info misc.SearchInfo) {
+			searchMu.Lock()
+			searchInfo = &info
+			searchMu.Unlock()
+		}, stop)
+		engineResults <- engineResult{session, moves, pos, err}
+	}()
+}
+
+// moveNow makes engine stop searching and play the best move found so far
+func moveNow() {
+	if thinking {
+		select {
+		case <-stopThinking:
+		default:
+			close(stopThinking)
+		}
+	}
+}
+
+// abortThinking stops engine search and drops its result
+func abortThinking() {
+	moveNow()
+	thinking = false
+}
+
+// engineMoved makes a move found by engine if the game hasn't changed since the search was started
+func engineMoved(result engineResult) {
+
+	if result.session != gameSession || result.moves != len(gameSession.History) || !thinking {
+		return
+	}
+
+	thinking = false
+
+	if !gameSession.EngineToMove() {
+		return
+	}
+
+	if result.err == nil {
+		result.err = gameSession.PlayAs(gameSession.Turn, result.pos)
+	}
+
+	if result.err != nil {
+		message = "Engine can't move: " + result.err.Error()
+		return
+	}
+
+	lastMoveTime = time.Now()
+
+	searchMu.Lock()
+	lastSearch = searchInfo
+	searchMu.Unlock()
+}
+
+// thinkingKey handles keys while engine thinks, board input is ignored
+func thinkingKey(ev termbox.Event) {
+	switch {
+	case ev.Key == termbox.KeyEsc:
+		abortThinking()
+		leaveGame()
+	case ev.Ch == 'm' || ev.Key == termbox.KeySpace || ev.Key == termbox.KeyEnter:
+		moveNow()
+		message = "Engine is moving"
+	default:
+		message = "Engine is thinking, press m to move now or Esc to abort the game"
+	}
+}
+
+// thinkingStatus describes current engine search
+func thinkingStatus() string {
+
+	status := fmt.Sprintf("%s is thinking... %.1fs", gameSession.Player(gameSession.Turn).Name,
+		time.Since(thinkingSince).Seconds())
+
+	searchMu.Lock()
+	defer searchMu.Unlock()
+
+	if searchInfo == nil {
+		return status
+	}
+
+	if searchInfo.Depth == 0 {
+		return status + ", estimated best " + board.Notation.Format(searchInfo.Best)
+	}
+
+	return status + fmt.Sprintf(", depth %d, best %s, score %d", searchInfo.Depth,
+		board.Notation.Format(searchInfo.Best), searchInfo.Score)
+}
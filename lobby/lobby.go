@@ -0,0 +1,615 @@
+// Package lobby is a multi-user game server for plain line-based clients like telnet
+//
+// After connecting a user enters a name and gets to the lobby, where open
+// tables can be listed, created, joined or watched. Each table is a game
+// session, a seat at a table can be taken by a user or by the computer.
+package lobby
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/risboo6909/goblin/misc"
+)
+
+const helpText = `Commands:
+  list                      list tables
+  create [option=value...]  create a table and sit at it, options are
+                            size=15, win=5, rules=freestyle|pro, side=x|o,
+                            first=x|o, vs=computer
+  join <table>              take a free seat at a table
+  watch <table>             watch a table
+  leave                     get back to the lobby
+  board                     show the board
+  move <cell>               make a move, like "move H8" or just "H8"
+  resign                    give up the game
+  say <text>                talk to everybody at the table
+  quit                      disconnect
+`
+
+// Server is a lobby server
+type Server struct {
+	// engine settings for computer players
+	MoveTime time.Duration
+	Depth    int
+
+	mu     sync.Mutex
+	tables map[int]*table
+	nextID int
+}
+
+// New creates a lobby server
+func New(moveTime time.Duration, depth int) *Server {
+	return &Server{MoveTime: moveTime, Depth: depth, tables: make(map[int]*table), nextID: 1}
+}
+
+// user is a connected client
+type user struct {
+	name string
+
+	out  chan string
+	done chan struct{}
+
+	// changed only by the goroutine serving the user
+	table *table
+	seat  misc.Cell
+}
+
+// printf sends text to a user, text is dropped if the user can't keep up
+func (u *user) printf(format string, args ...interface{}) {
+	select {
+	case u.out <- fmt.Sprintf(format, args...):
+	case <-u.done:
+	default:
+	}
+}
+
+// table is a game with its players and spectators
+type table struct {
+	id int
+
+	mu       sync.Mutex
+	session  *misc.Session
+	players  map[misc.Cell]*user
+	watchers map[*user]bool
+
+	// table has been removed from the lobby
+	closed bool
+}
+
+// Serve accepts connections until listener is closed
+func (srv *Server) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.handle(conn)
+	}
+}
+
+// cleanLine removes control characters and bytes left from telnet option negotiation
+func cleanLine(line string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < ' ' || r >= 0x7f {
+			return -1
+		}
+		return r
+	}, line))
+}
+
+func (srv *Server) handle(conn net.Conn) {
+
+	u := &user{out: make(chan string, 256), done: make(chan struct{}), seat: misc.E}
+
+	go func() {
+		for {
+			select {
+			case text := <-u.out:
+				if _, err := conn.Write([]byte(strings.Replace(text, "\n", "\r\n", -1))); err != nil {
+					return
+				}
+			case <-u.done:
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	defer close(u.done)
+
+	scanner := bufio.NewScanner(conn)
+
+	u.printf("Welcome to goblin!\nYour name: ")
+
+	for u.name == "" {
+		if !scanner.Scan() {
+			return
+		}
+		u.name = cleanLine(scanner.Text())
+	}
+
+	u.printf("Hello, %s! Type \"help\" to see commands.\n", u.name)
+	srv.list(u)
+
+	defer srv.leave(u)
+
+	for scanner.Scan() {
+		if line := cleanLine(scanner.Text()); line != "" && !srv.command(u, line) {
+			return
+		}
+	}
+}
+
+// command executes a user command, returns false if the user quits
+func (srv *Server) command(u *user, line string) bool {
+
+	fields := strings.Fields(line)
+	cmd, args := strings.ToLower(fields[0]), fields[1:]
+
+	switch cmd {
+
+	case "help", "?":
+		u.printf(helpText)
+
+	case "list", "ls":
+		srv.list(u)
+
+	case "create", "new":
+		srv.create(u, args)
+
+	case "join", "watch":
+		srv.join(u, args, cmd == "watch")
+
+	case "leave":
+		srv.leave(u)
+		u.printf("You are in the lobby\n")
+
+	case "board":
+		srv.atTable(u, func(t *table) {
+			u.printf("%s", t.describe())
+		})
+
+	case "move", "m":
+		if len(args) != 1 {
+			u.printf("Usage: move <cell>\n")
+			return true
+		}
+		srv.move(u, args[0])
+
+	case "resign":
+		srv.resign(u)
+
+	case "say":
+		text := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+		srv.atTable(u, func(t *table) {
+			t.broadcast("[%s] %s\n", u.name, text)
+		})
+
+	case "quit", "exit":
+		u.printf("Bye!\n")
+		return false
+
+	default:
+		// seated players may type cells without "move"
+		if u.seat != misc.E && len(fields) == 1 {
+			srv.move(u, fields[0])
+		} else {
+			u.printf("Unknown command %q, type \"help\" to see commands\n", cmd)
+		}
+	}
+
+	return true
+}
+
+// atTable calls fn with locked user's table
+func (srv *Server) atTable(u *user, fn func(t *table)) {
+	if u.table == nil {
+		u.printf("You are not at a table\n")
+		return
+	}
+	u.table.mu.Lock()
+	defer u.table.mu.Unlock()
+	fn(u.table)
+}
+
+// sortedTables returns tables ordered by ID
+func (srv *Server) sortedTables() []*table {
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	tables := make([]*table, 0, len(srv.tables))
+	for _, t := range srv.tables {
+		tables = append(tables, t)
+	}
+
+	sort.Slice(tables, func(i, j int) bool { return tables[i].id < tables[j].id })
+
+	return tables
+}
+
+func (srv *Server) list(u *user) {
+
+	tables := srv.sortedTables()
+
+	if len(tables) == 0 {
+		u.printf("No tables yet, type \"create\" to start one\n")
+		return
+	}
+
+	for _, t := range tables {
+		t.mu.Lock()
+		u.printf("%s\n", t.summary())
+		t.mu.Unlock()
+	}
+}
+
+// parseCreateOptions converts "option=value" arguments of create command into session options
+func (srv *Server) parseCreateOptions(args []string) (misc.SessionOptions, misc.Cell, bool, error) {
+
+	options := misc.SessionOptions{BoardSide: 15, WinLength: 5, Rules: misc.Freestyle, FirstMove: misc.X}
+	side, computer := misc.Cell(misc.X), false
+
+	for _, arg := range args {
+
+		parts := strings.SplitN(strings.ToLower(arg), "=", 2)
+		if len(parts) != 2 {
+			return options, side, computer, fmt.Errorf("expected option=value, got %q", arg)
+		}
+
+		var err error
+
+		switch key, value := parts[0], parts[1]; key {
+		case "size":
+			options.BoardSide, err = strconv.Atoi(value)
+		case "win":
+			options.WinLength, err = strconv.Atoi(value)
+		case "rules":
+			options.Rules, err = misc.ParseRules(value)
+		case "side":
+			side, err = parseSide(value)
+		case "first":
+			options.FirstMove, err = parseSide(value)
+		case "vs":
+			if computer = value == "computer"; !computer {
+				err = fmt.Errorf("can play only vs computer")
+			}
+		default:
+			err = fmt.Errorf("unknown option %q", key)
+		}
+
+		if err != nil {
+			return options, side, computer, err
+		}
+	}
+
+	if options.BoardSide < 3 || options.BoardSide > misc.MaxBoardSide {
+		return options, side, computer, fmt.Errorf("board size must be from 3 to %d", misc.MaxBoardSide)
+	}
+	if options.WinLength < 3 || options.WinLength > options.BoardSide {
+		return options, side, computer, fmt.Errorf("win length must be from 3 to board size")
+	}
+
+	return options, side, computer, nil
+}
+
+func parseSide(value string) (misc.Cell, error) {
+	switch value {
+	case "x":
+		return misc.X, nil
+	case "o":
+		return misc.O, nil
+	}
+	return misc.E, fmt.Errorf("unknown side %q", value)
+}
+
+func (srv *Server) create(u *user, args []string) {
+
+	options, side, computer, err := srv.parseCreateOptions(args)
+	if err != nil {
+		u.printf("%v\n", err)
+		return
+	}
+
+	srv.leave(u)
+
+	options.PlayerX, options.PlayerO = misc.HumanPlayer("X"), misc.HumanPlayer("O")
+
+	if computer {
+		engine := misc.EnginePlayer("computer", srv.Depth)
+		engine.AI.TimeLimit = srv.MoveTime
+		if side == misc.X {
+			options.PlayerO = engine
+		} else {
+			options.PlayerX = engine
+		}
+	}
+
+	t := &table{session: misc.NewSession(options), players: make(map[misc.Cell]*user),
+		watchers: make(map[*user]bool)}
+
+	srv.mu.Lock()
+	t.id = srv.nextID
+	srv.nextID++
+	srv.tables[t.id] = t
+	srv.mu.Unlock()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.players[side] = u
+	u.table, u.seat = t, side
+
+	u.printf("You sit at table #%d playing %c\n", t.id, side)
+	u.printf("%s", t.describe())
+
+	srv.engineTurn(t)
+}
+
+func (srv *Server) findTable(arg string) *table {
+
+	id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil {
+		return nil
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	return srv.tables[id]
+}
+
+func (srv *Server) join(u *user, args []string, watch bool) {
+
+	if len(args) != 1 {
+		u.printf("Usage: join <table> or watch <table>\n")
+		return
+	}
+
+	t := srv.findTable(args[0])
+	if t == nil {
+		u.printf("No table %s\n", args[0])
+		return
+	}
+
+	if u.table == t && (watch || u.seat != misc.E) {
+		u.printf("You are already at table #%d\n", t.id)
+		return
+	}
+
+	if u.table != t {
+		srv.leave(u)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		u.printf("No table %s\n", args[0])
+		return
+	}
+
+	if watch {
+		t.watchers[u] = true
+		u.table, u.seat = t, misc.E
+		t.broadcast("%s is watching\n", u.name)
+		u.printf("%s", t.describe())
+		return
+	}
+
+	seat := t.freeSeat()
+	if seat == misc.E {
+		u.printf("Table #%d is full, you can watch it\n", t.id)
+		return
+	}
+
+	// a spectator may take a free seat
+	delete(t.watchers, u)
+
+	t.players[seat] = u
+	u.table, u.seat = t, seat
+
+	t.broadcast("%s sits at table #%d playing %c\n", u.name, t.id, seat)
+	u.printf("%s", t.describe())
+}
+
+// leave takes user back to the lobby, a table is closed when the last user leaves it
+func (srv *Server) leave(u *user) {
+
+	t := u.table
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+
+	if u.seat != misc.E {
+		delete(t.players, u.seat)
+	}
+	delete(t.watchers, u)
+	u.table, u.seat = nil, misc.E
+
+	t.broadcast("%s has left the table\n", u.name)
+
+	empty := len(t.players) == 0 && len(t.watchers) == 0
+	t.closed = empty
+
+	t.mu.Unlock()
+
+	if empty {
+		srv.mu.Lock()
+		delete(srv.tables, t.id)
+		srv.mu.Unlock()
+	}
+}
+
+func (srv *Server) move(u *user, cell string) {
+	srv.atTable(u, func(t *table) {
+
+		s := t.session
+
+		switch {
+		case u.seat == misc.E:
+			u.printf("Spectators can't make moves\n")
+			return
+		case t.freeSeat() != misc.E && !s.Over():
+			u.printf("Waiting for an opponent\n")
+			return
+		case s.Turn != u.seat && !s.Over():
+			u.printf("It's not your turn\n")
+			return
+		}
+
+		pos, err := misc.DefaultNotation(s.Board.CellsVert).Parse(cell)
+		if err == nil {
+			err = s.Play(pos)
+		}
+		if err != nil {
+			u.printf("Can't move to %s: %v\n", cell, err)
+			return
+		}
+
+		t.moved()
+		srv.engineTurn(t)
+	})
+}
+
+func (srv *Server) resign(u *user) {
+	srv.atTable(u, func(t *table) {
+		if u.seat == misc.E {
+			u.printf("Spectators can't resign\n")
+			return
+		}
+		if err := t.session.Resign(u.seat); err != nil {
+			u.printf("Can't resign: %v\n", err)
+			return
+		}
+		t.broadcast("%s resigns\n", u.name)
+		t.broadcast("%s", t.describe())
+	})
+}
+
+// engineTurn lets computer move while it is its turn, table must be locked
+func (srv *Server) engineTurn(t *table) {
+	for t.session.EngineToMove() {
+		t.broadcast("Computer is thinking...\n")
+		if err := t.session.MakeMove(); err != nil {
+			t.broadcast("Computer can't move: %v\n", err)
+			return
+		}
+		t.moved()
+	}
+}
+
+// broadcast sends text to all users at a table, table must be locked
+func (t *table) broadcast(format string, args ...interface{}) {
+	for _, u := range t.players {
+		u.printf(format, args...)
+	}
+	for u := range t.watchers {
+		u.printf(format, args...)
+	}
+}
+
+// moved tells everybody at a table about the last move, table must be locked
+func (t *table) moved() {
+	s := t.session
+	move := s.History[len(s.History)-1]
+	t.broadcast("%s plays %s\n%s", t.playerName(move.Player),
+		misc.DefaultNotation(s.Board.CellsVert).Format(move.Pos), t.describe())
+}
+
+// freeSeat returns a side which may be taken by a user, E if there is none
+func (t *table) freeSeat() misc.Cell {
+	for _, side := range []misc.Cell{misc.X, misc.O} {
+		if t.session.Player(side).Kind == misc.Human && t.players[side] == nil {
+			return side
+		}
+	}
+	return misc.E
+}
+
+func (t *table) playerName(side misc.Cell) string {
+	if t.session.Player(side).Kind == misc.Engine {
+		return fmt.Sprintf("computer (%c)", side)
+	}
+	if u := t.players[side]; u != nil {
+		return fmt.Sprintf("%s (%c)", u.name, side)
+	}
+	return fmt.Sprintf("(open) (%c)", side)
+}
+
+// summary describes a table in one line for the lobby list
+func (t *table) summary() string {
+
+	s := t.session
+
+	state := fmt.Sprintf("move %d", len(s.History)+1)
+	switch {
+	case t.freeSeat() != misc.E && !s.Over():
+		state = "waiting for a player"
+	case s.Over():
+		state = t.result()
+	}
+
+	return fmt.Sprintf("#%d  %s vs %s  %dx%d, %d in a row, %s rules, %d watching, %s",
+		t.id, t.playerName(misc.X), t.playerName(misc.O), s.Board.CellsHoriz, s.Board.CellsVert,
+		s.WinLength, s.Rules, len(t.watchers), state)
+}
+
+// result describes a finished game
+func (t *table) result() string {
+	s := t.session
+	switch s.Status {
+	case misc.GameWon:
+		return t.playerName(s.Winner) + " wins"
+	case misc.GameResigned:
+		return t.playerName(misc.Opponent(s.Winner)) + " resigned, " + t.playerName(s.Winner) + " wins"
+	case misc.GameTimeout:
+		return t.playerName(misc.Opponent(s.Winner)) + " ran out of time, " + t.playerName(s.Winner) + " wins"
+	case misc.GameDraw:
+		return "draw"
+	}
+	return "in progress"
+}
+
+// describe renders the board with coordinates and the game state
+func (t *table) describe() string {
+
+	s := t.session
+	notation := misc.DefaultNotation(s.Board.CellsVert)
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Table #%d: %s vs %s\n", t.id, t.playerName(misc.X), t.playerName(misc.O))
+
+	b.WriteString("   ")
+	for col := 0; col < s.Board.CellsHoriz; col++ {
+		b.WriteString(" " + notation.ColumnName(col))
+	}
+	b.WriteString("\n")
+
+	// the first line of the diagram is a header
+	lines := strings.Split(strings.TrimRight(s.Board.String(), "\n"), "\n")
+	for row, line := range lines[1:] {
+		fmt.Fprintf(&b, "%3s%s\n", notation.RowName(row), line)
+	}
+
+	if s.Over() {
+		b.WriteString("Game over: " + t.result() + "\n")
+		for _, interval := range s.Intervals {
+			fmt.Fprintf(&b, "Winning line %s-%s\n", notation.Format(interval.From), notation.Format(interval.To))
+		}
+	} else {
+		fmt.Fprintf(&b, "%s to move\n", t.playerName(s.Turn))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,170 @@
+package lobby
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// client is a line-based test client
+type client struct {
+	t      *testing.T
+	name   string
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func startServer(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go New(100*time.Millisecond, 1).Serve(ln)
+	return ln
+}
+
+func connect(t *testing.T, ln net.Listener, name string) *client {
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &client{t, name, conn, bufio.NewReader(conn)}
+	c.send(name)
+	c.expect("Hello, " + name)
+	return c
+}
+
+func (c *client) send(line string) {
+	fmt.Fprintf(c.conn, "%s\r\n", line)
+}
+
+// expect reads lines until one of them contains text and returns all lines read
+func (c *client) expect(text string) []string {
+	lines := []string{}
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			c.t.Fatalf("%s: waiting for %q: %v, got %q", c.name, text, err, lines)
+		}
+		lines = append(lines, strings.TrimRight(line, "\r\n"))
+		if strings.Contains(line, text) {
+			return lines
+		}
+	}
+}
+
+func TestLobbyGame(t *testing.T) {
+
+	ln := startServer(t)
+	defer ln.Close()
+
+	alice := connect(t, ln, "alice")
+	alice.expect("No tables yet")
+
+	alice.send("create size=7 win=4")
+	alice.expect("You sit at table #1 playing X")
+
+	bob := connect(t, ln, "bob")
+	bob.expect("#1  alice (X) vs (open) (O)  7x7, 4 in a row, freestyle rules, 0 watching, waiting for a player")
+
+	// nobody to play with yet
+	alice.send("A1")
+	alice.expect("Waiting for an opponent")
+
+	bob.send("join 1")
+	alice.expect("bob sits at table #1 playing O")
+
+	carol := connect(t, ln, "carol")
+	carol.send("join 1")
+	carol.expect("Table #1 is full")
+	carol.send("watch 1")
+	carol.expect("carol is watching")
+
+	// board diagram from the board description
+	lines := carol.expect("to move")
+	if len(lines) != 10 || lines[1] != "    A B C D E F G" || lines[2] != "  1 . . . . . . ." {
+		t.Fatalf("unexpected board %q", lines)
+	}
+
+	carol.send("move A1")
+	carol.expect("Spectators can't make moves")
+
+	bob.send("move A7")
+	bob.expect("It's not your turn")
+
+	for i, cell := range []string{"A1", "A7", "B1", "B7", "C1", "C7", "D1"} {
+		player := alice
+		if i%2 == 1 {
+			player = bob
+		}
+		player.send(cell)
+		carol.expect(" plays " + cell)
+	}
+
+	lines = carol.expect("Game over: alice (X) wins")
+	if lines[len(lines)-2] != "  7 O O O . . . ." {
+		t.Fatalf("unexpected board %q", lines)
+	}
+	carol.expect("Winning line A1-D1")
+
+	bob.send("say gg")
+	alice.expect("[bob] gg")
+
+	alice.send("list")
+	alice.expect("#1  alice (X) vs bob (O)  7x7, 4 in a row, freestyle rules, 1 watching, alice (X) wins")
+
+	// the table is closed when everybody leaves
+	for _, c := range []*client{alice, bob, carol} {
+		c.send("leave")
+		c.expect("You are in the lobby")
+	}
+	alice.send("list")
+	alice.expect("No tables yet")
+
+	alice.send("quit")
+	alice.expect("Bye!")
+}
+
+func TestLobbyVsComputer(t *testing.T) {
+
+	ln := startServer(t)
+	defer ln.Close()
+
+	alice := connect(t, ln, "alice")
+
+	alice.send("create size=7 win=4 side=o vs=computer")
+	alice.expect("You sit at table #1 playing O")
+
+	// computer moves first
+	alice.expect("computer (X) plays")
+	alice.expect("alice (O) to move")
+
+	alice.send("resign")
+	alice.expect("Game over: alice (O) resigned, computer (X) wins")
+}
+
+func TestLobbyBadCommands(t *testing.T) {
+
+	ln := startServer(t)
+	defer ln.Close()
+
+	alice := connect(t, ln, "alice")
+
+	for command, reply := range map[string]string{
+		"create size=40":   "board size must be from 3 to 25",
+		"create vs=human":  "can play only vs computer",
+		"create colour=x":  `unknown option "colour"`,
+		"join 5":           "No table 5",
+		"board":            "You are not at a table",
+		"dance":            `Unknown command "dance"`,
+		"move":             "Usage: move <cell>",
+		"create win=3 foo": `expected option=value, got "foo"`,
+	} {
+		alice.send(command)
+		alice.expect(reply)
+	}
+}
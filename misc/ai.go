@@ -1,11 +1,13 @@
 package misc
 
 import (
-	"fmt"
+	"errors"
 	"math"
 	"math/rand"
 	"runtime"
 	"sort"
+	"sync"
+	"time"
 )
 
 // KMPPrefixTable is a helper function for KMPSearch that generates
@@ -244,6 +246,11 @@ func switchPlayer(player Cell) Cell {
 	return X
 }
 
+// Opponent returns the side playing against a given one
+func Opponent(player Cell) Cell {
+	return switchPlayer(player)
+}
+
 // checkWin determines whether there is N in-a-row Xs or Os on a board
 // which would mean that there is a winner and the game is over
 func checkWin(board *BoardDescription, player Cell) (bool, IntervalList) {
@@ -328,6 +335,13 @@ func MonteCarloEval(board *BoardDescription, options AIOptions, maxDepth, trials
 
 			sem <- 1
 
+			// no more trials when out of time
+			if options.timeIsUp() {
+				out <- trialType{}
+				<-sem
+				return
+			}
+
 			// clone existing board
 			clonedBoard := CloneBoard(board)
 
@@ -509,6 +523,11 @@ func MinMaxEval(board *BoardDescription, options AIOptions, cellsToCheck []int,
 
 			for idx, cellIdx := range cellsToCheck {
 
+				// unfinished search is discarded by BestMove
+				if options.timeIsUp() {
+					break
+				}
+
 				if cellsGen {
 					// swap index in value in case of intRange generator
 					if boardCopy.GetCellLinear(idx) != E { continue }
@@ -550,47 +569,314 @@ func MinMaxEval(board *BoardDescription, options AIOptions, cellsToCheck []int,
 
 }
 
-// Function to choose the best move from a given position
-func MakeMove(board *BoardDescription, options AIOptions) (Cell, []Interval) {
+// openingMove returns a book move for the first two moves of a game, it takes
+// the center on an empty board and replies diagonally next to the only stone
+// on a board, choosing a side closer to the center
+func openingMove(board *BoardDescription) (CellPosition, bool) {
+
+	center := boardCenter(board)
+	occupied := board.GetOccupiedIndices()
+
+	switch len(occupied) {
+
+	case 0:
+		return center, true
+
+	case 1:
+		col, row, _ := board.FromLinear(occupied[0])
+
+		dCol, dRow := 1, -1
+		if col > center.Col {
+			dCol = -1
+		}
+		if row < center.Row {
+			dRow = 1
+		}
+
+		for _, delta := range []CellPosition{{dCol, dRow}, {-dCol, dRow}, {dCol, -dRow}, {-dCol, -dRow}} {
+			pos := CellPosition{col + delta.Col, row + delta.Row}
+			if _, err := board.ToLinear(pos.Col, pos.Row); err == nil {
+				return pos, true
+			}
+		}
+	}
+
+	return CellPosition{}, false
+}
+
+// lineLength returns the length of player's line through pos in direction
+// (dCol, dRow) if player puts a stone at pos
+func lineLength(board *BoardDescription, pos CellPosition, dCol, dRow int, player Cell) int {
+
+	length := 1
+
+	for _, sign := range []int{1, -1} {
+		col, row := pos.Col+sign*dCol, pos.Row+sign*dRow
+		for {
+			idx, err := board.ToLinear(col, row)
+			if err != nil || board.GetCellLinear(idx) != player {
+				break
+			}
+			length++
+			col, row = col+sign*dCol, row+sign*dRow
+		}
+	}
+
+	return length
+}
+
+// winningCell returns a free cell which completes a line of player, false if there is none
+func winningCell(board *BoardDescription, player Cell, winLength int) (int, bool) {
+
+	for _, idx := range board.GetFreeIndices() {
+		col, row, _ := board.FromLinear(idx)
+		for _, dir := range [][2]int{{1, 0}, {0, 1}, {1, 1}, {1, -1}} {
+			if lineLength(board, CellPosition{col, row}, dir[0], dir[1], player) >= winLength {
+				return idx, true
+			}
+		}
+	}
+
+	return -1, false
+}
+
+// urgentMove returns a move which wins at once or, failing that, blocks
+// opponent's win in one, such moves need no search
+func urgentMove(board *BoardDescription, options AIOptions) (int, int, bool) {
+
+	if idx, found := winningCell(board, options.AIPlayer, options.winSequenceLength); found {
+		return idx, WON, true
+	}
+
+	if idx, found := winningCell(board, switchPlayer(options.AIPlayer), options.winSequenceLength); found {
+		return idx, NOTHING, true
+	}
+
+	return -1, NOTHING, false
+}
+
+// fallbackMove returns a free cell with the most stones around it, it is
+// played when search has no time to rate any cell
+func fallbackMove(board *BoardDescription) int {
+
+	best, bestNeighbours := -1, -1
+
+	for _, idx := range board.GetFreeIndices() {
+
+		col, row, _ := board.FromLinear(idx)
+		neighbours := 0
+
+		for dCol := -1; dCol <= 1; dCol++ {
+			for dRow := -1; dRow <= 1; dRow++ {
+				if n, err := board.ToLinear(col+dCol, row+dRow); err == nil && board.GetCellLinear(n) != E {
+					neighbours++
+				}
+			}
+		}
+
+		if neighbours > bestNeighbours {
+			best, bestNeighbours = idx, neighbours
+		}
+	}
+
+	return best
+}
+
+// engine keeps winning patterns in package variables, so only one search may
+// run at a time
+var engineLock sync.Mutex
+
+// BestMove searches for the best move for options.AIPlayer without modifying the board,
+// if options.TimeLimit is set search goes deeper while there is time left
+func BestMove(board *BoardDescription, options AIOptions) (CellPosition, error) {
+	return BestMoveProgress(board, options, nil)
+}
+
+// BestMoveProgress is BestMove which reports search progress to a given function
+func BestMoveProgress(board *BoardDescription, options AIOptions, progress ProgressFunc) (CellPosition, error) {
+
+	engineLock.Lock()
+	defer engineLock.Unlock()
 
 	generateWinningPatterns(options.winSequenceLength)
 
-	// Use Monte-Carlo for static evaluation
+	if board.NumFreeCells() == 0 {
+		return CellPosition{}, errors.New("No free cells left")
+	}
 
-	opponent := switchPlayer(options.AIPlayer)
+	if move, found := openingMove(board); found {
+		return move, nil
+	}
 
-	playerWon, intervals := checkWin(board, opponent)
+	start := time.Now()
 
-	if playerWon {
-		return opponent, intervals
+	// wins and forced blocks are played even if search is stopped or short of time
+	if idx, val, found := urgentMove(board, options); found {
+		progress.report(board, 1, idx, val, start)
+		col, row, _ := board.FromLinear(idx)
+		return CellPosition{col, row}, nil
 	}
 
-	cellsToCheck := ReduceSearchSpaceMonteCarlo(board, options, 500, 0.1)
+	// Use Monte-Carlo for static evaluation, it may take up to a half of the time
+	// available for a move, stop is not honored here, so that a stopped search
+	// still has rated candidates to choose from
+
+	mcOptions := options
+	mcOptions.stop = nil
+
+	if options.TimeLimit > 0 {
+		mcOptions.deadline = start.Add(options.TimeLimit / 2)
+	}
+
+	cellsToCheck := ReduceSearchSpaceMonteCarlo(board, mcOptions, 500, 0.1)
 
 	if len(cellsToCheck) == 0 {
 		cellsToCheck = nil
 	}
 
-	fmt.Println(cellsToCheck)
+	notation := DefaultNotation(board.CellsVert)
+
+	candidates := make([]string, len(cellsToCheck))
+	for i, idx := range cellsToCheck {
+		col, row, _ := board.FromLinear(idx)
+		candidates[i] = notation.Format(CellPosition{col, row})
+	}
+	logger.Printf("candidate moves: %v", candidates)
+
+	if len(cellsToCheck) != 0 {
+		progress.report(board, 0, cellsToCheck[0], NOTHING, start)
+	}
+
+	bestLinear, bestVal := -1, LOST
+
+	if options.TimeLimit <= 0 {
+
+		bestLinear, bestVal = MinMaxEval(board, options, cellsToCheck,
+			LinearMove{0, options.AIPlayer}, options.maxDepth)
 
-	bestLinear, bestVal := MinMaxEval(board, options, cellsToCheck,
-		LinearMove{0, options.AIPlayer}, options.maxDepth)
+		// result of a stopped search is dropped as well
+		if options.timeIsUp() {
+			bestLinear, bestVal = -1, LOST
+		} else {
+			progress.report(board, options.maxDepth, bestLinear, bestVal, start)
+		}
+
+	} else {
+
+		options.deadline = start.Add(options.TimeLimit)
+
+		// iterative deepening, result of a search interrupted by deadline is dropped
+		for depth := 1; depth <= options.maxDepth; depth++ {
+
+			move, val := MinMaxEval(board, options, cellsToCheck,
+				LinearMove{0, options.AIPlayer}, depth)
+
+			if options.timeIsUp() {
+				break
+			}
+
+			bestLinear, bestVal = move, val
+			logger.Printf("depth %d done in %v", depth, time.Since(start))
+			progress.report(board, depth, bestLinear, bestVal, start)
+
+			if val == WON || val == LOST {
+				break
+			}
+		}
+	}
 
 	col, row, err := board.FromLinear(bestLinear)
 
-	if err == nil && board.GetCell(col, row) == E {
-		bestMove := CellPosition{col, row}
-		fmt.Println(bestLinear, bestMove, bestVal)
-		board.SetCell(bestMove.Col, bestMove.Row, options.AIPlayer)
+	if err != nil || board.GetCell(col, row) != E {
+
+		// search had no time to finish, take the best Monte-Carlo candidate
+		// or a cell next to stones
+		if options.TimeLimit <= 0 && !options.timeIsUp() {
+			return CellPosition{}, errors.New("Search returned an invalid move")
+		}
+
+		if len(cellsToCheck) != 0 {
+			bestLinear = cellsToCheck[0]
+		} else {
+			bestLinear = fallbackMove(board)
+		}
+
+		col, row, _ = board.FromLinear(bestLinear)
+	}
+
+	bestMove := CellPosition{col, row}
+	logger.Printf("best move for %c: %v, score %v", options.AIPlayer, notation.Format(bestMove), bestVal)
+
+	return bestMove, nil
+}
+
+// RankMoves rates free cells using Monte-Carlo evaluation, the best cells go first
+func RankMoves(board *BoardDescription, options AIOptions, trials int) IntFloatPairs {
 
+	engineLock.Lock()
+	defer engineLock.Unlock()
+
+	generateWinningPatterns(options.winSequenceLength)
+
+	arranged := ArrangeMonteCarloResults(board, options, board.NumFreeCells(), trials, options.AIPlayer)
+
+	// occupied cells are left as zero values by ArrangeMonteCarloResults
+	result, seen := make(IntFloatPairs, 0, len(arranged)), make(Set)
+
+	for _, move := range arranged {
+		if _, found := seen[move.Fst]; !found && board.GetCellLinear(move.Fst) == E {
+			seen[move.Fst] = true
+			result = append(result, move)
+		}
+	}
+
+	return result
+}
+
+// EvalCells rates every cell of the board by Monte-Carlo evaluation for
+// options.AIPlayer moving first, occupied cells are rated zero
+func EvalCells(board *BoardDescription, options AIOptions, trials int) []float64 {
+
+	engineLock.Lock()
+	defer engineLock.Unlock()
+
+	generateWinningPatterns(options.winSequenceLength)
+
+	scores := MonteCarloEval(board, options, board.NumFreeCells(), trials, options.AIPlayer)
+
+	for idx := range scores {
+		// scores are NaN if no trial has ended with a win
+		if board.GetCellLinear(idx) != E || math.IsNaN(scores[idx]) {
+			scores[idx] = 0
+		}
+	}
+
+	return scores
+}
+
+// Function to choose the best move from a given position
+func MakeMove(board *BoardDescription, options AIOptions) (Cell, []Interval) {
+
+	opponent := switchPlayer(options.AIPlayer)
+
+	// winning patterns are built here as BestMove owns the global ones
+	intervals := FindPattern(board, winPattern(opponent, options.winSequenceLength))
+
+	if len(intervals) != 0 {
+		return opponent, intervals
+	}
+
+	bestMove, err := BestMove(board, options)
+
+	if err == nil {
+		board.SetCell(bestMove.Col, bestMove.Row, options.AIPlayer)
 	} else {
-		// TODO: Add proper logging
-		fmt.Println("ERROR")
+		logger.Printf("no move for %c: %v", options.AIPlayer, err)
 	}
 
-	AIWon, intervals := checkWin(board, options.AIPlayer)
+	intervals = FindPattern(board, winPattern(options.AIPlayer, options.winSequenceLength))
 
-	if AIWon {
+	if len(intervals) != 0 {
 		return options.AIPlayer, intervals
 	}
 
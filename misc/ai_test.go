@@ -5,6 +5,7 @@ import (
 
 	"reflect"
 	"sort"
+	"time"
 )
 
 func TestFindChainDiagonal(t *testing.T) {
@@ -344,6 +345,57 @@ func TestMinMaxEval(t *testing.T) {
 }
 
 
+func TestOpeningMove(t *testing.T) {
+
+	board := NewBoard(15, 15)
+
+	move, found := openingMove(board)
+	assertEqual(t, found, true)
+	assertEqual(t, move, CellPosition{7, 7})
+
+	board.SetCell(7, 7, X)
+	move, _ = openingMove(board)
+	assertEqual(t, move, CellPosition{8, 6})
+
+	board = NewBoard(15, 15)
+	board.SetCell(14, 0, X)
+	move, _ = openingMove(board)
+	assertEqual(t, move, CellPosition{13, 1})
+
+	board.SetCell(13, 1, O)
+	_, found = openingMove(board)
+	assertEqual(t, found, false)
+}
+
+func TestBestMoveProgress(t *testing.T) {
+
+	board := NewBoard(7, 7)
+	board.SetCell(3, 3, X)
+	board.SetCell(4, 4, O)
+
+	options := NewAIOptions(X, 4, 2)
+	options.TimeLimit = 5 * time.Second
+
+	infos := []SearchInfo{}
+	move, err := BestMoveProgress(board, options, func(info SearchInfo) {
+		infos = append(infos, info)
+	})
+	assertNoError(t, err)
+
+	if len(infos) == 0 {
+		t.Fatal("no progress reported")
+	}
+
+	// depth grows and the last estimate is the chosen move
+	for i := 1; i < len(infos); i++ {
+		if infos[i].Depth <= infos[i-1].Depth {
+			t.Fatalf("depth doesn't grow: %+v", infos)
+		}
+	}
+	assertEqual(t, infos[len(infos)-1].Best, move)
+}
+
+
 // Some benchmarks
 
 // Monte-Carlo benchmarks start >>>
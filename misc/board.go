@@ -2,8 +2,10 @@ package misc
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"math/rand"
+	"strings"
 )
 
 const (
@@ -310,3 +312,181 @@ func (p *BoardDescription) String() string {
 	}
 	return repr
 }
+
+// BoardParseError describes a position in a board diagram where parsing has failed,
+// Line and Col are counted from 1
+type BoardParseError struct {
+	Line, Col int
+	Msg       string
+}
+
+func (e *BoardParseError) Error() string {
+	return fmt.Sprintf("line %d, col %d: %s", e.Line, e.Col, e.Msg)
+}
+
+// parseSideToMove parses side to move marker which is either "X" or "O"
+func parseSideToMove(marker string) (Cell, bool) {
+	switch strings.ToUpper(marker) {
+	case "X":
+		return X, true
+	case "O":
+		return O, true
+	}
+	return E, false
+}
+
+// boardFromRows builds a board from rows of equal length
+func boardFromRows(rows [][]Cell) *BoardDescription {
+	board := NewBoard(len(rows[0]), len(rows))
+	for row, cells := range rows {
+		for col, cell := range cells {
+			board.SetCell(col, row, cell)
+		}
+	}
+	return board
+}
+
+// ParseBoard is the inverse of String, besides the multiline form it accepts compact
+// single line form like "3X/.O1/... O" where rows are separated by slashes and digits
+// stand for a number of empty cells. Side to move may be given by a "X to move" line
+// after the rows or by a trailing "X" or "O" in the compact form, E is returned when
+// it is not set
+func ParseBoard(diagram string) (*BoardDescription, Cell, error) {
+	if !strings.Contains(strings.TrimSpace(diagram), "\n") {
+		return parseCompactBoard(strings.TrimSpace(diagram))
+	}
+	return parseBoardDiagram(diagram)
+}
+
+func parseBoardDiagram(diagram string) (*BoardDescription, Cell, error) {
+
+	rows := [][]Cell{}
+	sideToMove := Cell(E)
+
+	for lineIdx, line := range strings.Split(diagram, "\n") {
+
+		line = strings.TrimRight(line, "\r")
+		fields := strings.Fields(line)
+
+		if len(fields) == 0 || (len(rows) == 0 && strings.TrimSpace(line) == "Board") {
+			continue
+		}
+
+		if len(fields) == 3 && fields[1] == "to" && fields[2] == "move" {
+			side, ok := parseSideToMove(fields[0])
+			if !ok {
+				return nil, E, &BoardParseError{lineIdx + 1, strings.Index(line, fields[0]) + 1,
+					"unknown side " + fields[0]}
+			}
+			sideToMove = side
+			continue
+		}
+
+		if sideToMove != E {
+			return nil, E, &BoardParseError{lineIdx + 1, 1, "board rows after side to move"}
+		}
+
+		row := make([]Cell, 0, len(fields))
+
+		for col, c := range line {
+			switch c {
+			case ' ', '\t':
+				continue
+			case '.':
+				row = append(row, E)
+			case 'X', 'x':
+				row = append(row, X)
+			case 'O', 'o':
+				row = append(row, O)
+			default:
+				return nil, E, &BoardParseError{lineIdx + 1, col + 1, fmt.Sprintf("unexpected %q", c)}
+			}
+		}
+
+		if len(rows) != 0 && len(row) != len(rows[0]) {
+			return nil, E, &BoardParseError{lineIdx + 1, 1,
+				fmt.Sprintf("row has %d cells instead of %d", len(row), len(rows[0]))}
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, E, &BoardParseError{1, 1, "no board rows found"}
+	}
+
+	return boardFromRows(rows), sideToMove, nil
+}
+
+func parseCompactBoard(diagram string) (*BoardDescription, Cell, error) {
+
+	rows := [][]Cell{{}}
+	sideToMove := Cell(E)
+
+	if idx := strings.IndexByte(diagram, ' '); idx >= 0 {
+		marker := strings.TrimSpace(diagram[idx:])
+		side, ok := parseSideToMove(marker)
+		if !ok {
+			return nil, E, &BoardParseError{1, strings.Index(diagram, marker) + 1, "unknown side " + marker}
+		}
+		diagram, sideToMove = diagram[:idx], side
+	}
+
+	empty := 0
+
+	// flush pending number of empty cells
+	flush := func() {
+		for ; empty > 0; empty-- {
+			rows[len(rows)-1] = append(rows[len(rows)-1], E)
+		}
+	}
+
+	for col, c := range diagram {
+
+		if c >= '0' && c <= '9' {
+			empty = empty*10 + int(c-'0')
+			// runs of empty cells are bounded by the first row, or by the largest board
+			// before it ends, so that huge numbers neither exhaust memory nor overflow
+			width := MaxBoardSide
+			if len(rows) > 1 {
+				width = len(rows[0])
+			}
+			if len(rows[len(rows)-1])+empty > width {
+				return nil, E, &BoardParseError{1, col + 1, "too many empty cells"}
+			}
+			continue
+		}
+
+		flush()
+
+		switch c {
+		case '/':
+			if len(rows) > 1 && len(rows[len(rows)-1]) != len(rows[0]) {
+				return nil, E, &BoardParseError{1, col + 1, "rows of different length"}
+			}
+			rows = append(rows, []Cell{})
+		case '.':
+			rows[len(rows)-1] = append(rows[len(rows)-1], E)
+		case 'X', 'x':
+			rows[len(rows)-1] = append(rows[len(rows)-1], X)
+		case 'O', 'o':
+			rows[len(rows)-1] = append(rows[len(rows)-1], O)
+		default:
+			return nil, E, &BoardParseError{1, col + 1, fmt.Sprintf("unexpected %q", c)}
+		}
+	}
+
+	flush()
+
+	for _, row := range rows {
+		if len(row) == 0 || len(row) != len(rows[0]) {
+			return nil, E, &BoardParseError{1, len(diagram) + 1, "rows of different length"}
+		}
+	}
+
+	if len(rows) > MaxBoardSide || len(rows[0]) > MaxBoardSide {
+		return nil, E, &BoardParseError{1, len(diagram) + 1, "board is too large"}
+	}
+
+	return boardFromRows(rows), sideToMove, nil
+}
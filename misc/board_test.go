@@ -96,3 +96,76 @@ func TestDiagonalSlices(t *testing.T) {
 	}
 
 }
+
+func TestParseBoard(t *testing.T) {
+
+	board, side, err := ParseBoard(`
+		Board
+		 . X O .
+		 . . x .
+		 o . . X
+		O to move
+	`)
+
+	assertNoError(t, err)
+	assertEqual(t, side, Cell(O))
+	assertEqual(t, board.CellsHoriz, 4)
+	assertEqual(t, board.CellsVert, 3)
+	assertEqual(t, board.Content, []Cell{E, X, O, E, E, E, X, E, O, E, E, X})
+
+	compact, side, err := ParseBoard(".XO./2X1/o2X O")
+	assertNoError(t, err)
+	assertEqual(t, side, Cell(O))
+	assertEqual(t, compact.Content, board.Content)
+
+	// no side to move
+	_, side, err = ParseBoard("X.O/.../...")
+	assertNoError(t, err)
+	assertEqual(t, side, Cell(E))
+
+	// empty cells count may be longer than one digit
+	compact, _, err = ParseBoard("12X/13")
+	assertNoError(t, err)
+	assertEqual(t, compact.CellsHoriz, 13)
+	assertEqual(t, compact.GetCell(12, 0), Cell(X))
+
+	// String output is parsed back
+	for i := 0; i < 100; i++ {
+		board := GetRandomizedBoard(7, 5, 40.0)
+		parsed, _, err := ParseBoard(board.String())
+		assertNoError(t, err)
+		assertEqual(t, parsed.CellsHoriz, 7)
+		assertEqual(t, parsed.Content, board.Content)
+	}
+}
+
+func TestParseBoardErrors(t *testing.T) {
+
+	diagrams := map[string]BoardParseError{
+		"Board\n . X\n . Z\n":     {3, 4, ""},
+		" . X\n . X .\n":          {2, 1, ""},
+		" . X\n . X\nZ to move\n": {3, 1, ""},
+		" . X\nX to move\n . X\n": {3, 1, ""},
+		"\n\n":                    {1, 1, ""},
+		"..X/.Z.":                 {1, 6, ""},
+		"..X/.. X":                {1, 7, ""},
+		"..X/... Y":               {1, 9, ""},
+		"..X//...":                {1, 5, ""},
+		"999999999999/...":        {1, 2, ""},
+		"..X/.4":                  {1, 6, ""},
+		"3/99999999999999999999":  {1, 3, ""},
+		"99999999999999999999999": {1, 2, ""},
+		"25X":                     {1, 4, ""},
+	}
+
+	for diagram, expected := range diagrams {
+		_, _, err := ParseBoard(diagram)
+		parseErr, ok := err.(*BoardParseError)
+		if !ok {
+			t.Fatalf("Unexpected error %v for %q", err, diagram)
+		}
+		if parseErr.Line != expected.Line || parseErr.Col != expected.Col {
+			t.Fatalf("Wrong error position %v for %q", parseErr, diagram)
+		}
+	}
+}
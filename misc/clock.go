@@ -0,0 +1,235 @@
+package misc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeControlKind tells how players' clocks are run
+type TimeControlKind uint8
+
+const (
+	// NoTimeControl means that moves are not timed
+	NoTimeControl TimeControlKind = iota
+	// SuddenDeath gives each player Main time for the whole game
+	SuddenDeath
+	// Fischer gives each player Main time and adds Increment after each move
+	Fischer
+	// FixedPerMove gives each player Main time for every move, unused time is not kept
+	FixedPerMove
+)
+
+// TimeControl describes time given to each player
+type TimeControl struct {
+	Kind      TimeControlKind
+	Main      time.Duration
+	Increment time.Duration
+}
+
+// Timed returns true if moves are timed
+func (tc TimeControl) Timed() bool {
+	return tc.Kind != NoTimeControl
+}
+
+// String formats time control the way ParseTimeControl reads it
+func (tc TimeControl) String() string {
+	switch tc.Kind {
+	case SuddenDeath:
+		return tc.Main.String()
+	case Fischer:
+		return tc.Main.String() + "+" + tc.Increment.String()
+	case FixedPerMove:
+		return tc.Main.String() + "/move"
+	}
+	return "none"
+}
+
+// ParseTimeControl reads time control from a string, "5m" is sudden death,
+// "5m+3s" is Fischer with 3s increment, "10s/move" is fixed time per move
+// and "none" or an empty string means no time control
+func ParseTimeControl(s string) (TimeControl, error) {
+
+	s = strings.TrimSpace(s)
+
+	if s == "" || s == "none" {
+		return TimeControl{}, nil
+	}
+
+	tc := TimeControl{Kind: SuddenDeath}
+	main := s
+
+	if strings.HasSuffix(s, "/move") {
+		tc.Kind = FixedPerMove
+		main = strings.TrimSuffix(s, "/move")
+	} else if i := strings.Index(s, "+"); i >= 0 {
+		tc.Kind = Fischer
+		main = s[:i]
+		increment, err := time.ParseDuration(s[i+1:])
+		if err != nil || increment < 0 {
+			return TimeControl{}, fmt.Errorf("invalid time increment in %q", s)
+		}
+		tc.Increment = increment
+	}
+
+	var err error
+	if tc.Main, err = time.ParseDuration(main); err != nil || tc.Main <= 0 {
+		return TimeControl{}, fmt.Errorf("invalid time control %q", s)
+	}
+
+	return tc, nil
+}
+
+// Clock keeps time left to a player
+type Clock struct {
+	// time left when the clock was stopped last time
+	Left time.Duration
+
+	// when the clock has been started, zero if it is stopped
+	started time.Time
+}
+
+// timeNow returns current time, tests replace it to run clocks by hand
+var timeNow = time.Now
+
+// Running returns true if the clock is ticking
+func (c *Clock) Running() bool {
+	return !c.started.IsZero()
+}
+
+// Remaining returns time left at a given moment, it is never negative
+func (c *Clock) Remaining(now time.Time) time.Duration {
+	left := c.Left
+	if c.Running() {
+		left -= now.Sub(c.started)
+	}
+	if left < 0 {
+		left = 0
+	}
+	return left
+}
+
+func (c *Clock) start(now time.Time) {
+	if !c.Running() {
+		c.started = now
+	}
+}
+
+func (c *Clock) stop(now time.Time) {
+	c.Left = c.Remaining(now)
+	c.started = time.Time{}
+}
+
+// Clock returns clock of a player playing for side
+func (s *Session) Clock(side Cell) *Clock {
+	if side == O {
+		return &s.ClockO
+	}
+	return &s.ClockX
+}
+
+// TimeLeft returns time left to a player playing for side, it is zero if moves are not timed
+func (s *Session) TimeLeft(side Cell) time.Duration {
+	return s.Clock(side).Remaining(timeNow())
+}
+
+// resetClocks sets both clocks to the initial time and starts the clock of the side to move
+func (s *Session) resetClocks() {
+
+	s.ClockX, s.ClockO = Clock{}, Clock{}
+
+	if !s.TimeControl.Timed() {
+		return
+	}
+
+	s.ClockX.Left, s.ClockO.Left = s.TimeControl.Main, s.TimeControl.Main
+
+	if !s.Over() {
+		s.Clock(s.Turn).start(timeNow())
+	}
+}
+
+// stopClocks stops both clocks, time spent so far is taken from the running one
+func (s *Session) stopClocks() {
+	now := timeNow()
+	s.ClockX.stop(now)
+	s.ClockO.stop(now)
+}
+
+// switchClocks is called after player has moved, it stops player's clock, adds
+// an increment and starts the clock of the side to move if the game goes on
+func (s *Session) switchClocks(player Cell) {
+
+	if !s.TimeControl.Timed() {
+		return
+	}
+
+	s.stopClocks()
+
+	clock := s.Clock(player)
+
+	switch s.TimeControl.Kind {
+	case Fischer:
+		clock.Left += s.TimeControl.Increment
+	case FixedPerMove:
+		clock.Left = s.TimeControl.Main
+	}
+
+	if !s.Over() {
+		s.Clock(s.Turn).start(timeNow())
+	}
+}
+
+// CheckTime ends the game with a loss of the side to move if its time is up,
+// returns true if the flag has fallen
+func (s *Session) CheckTime() bool {
+
+	if s.Over() || !s.TimeControl.Timed() || s.TimeLeft(s.Turn) > 0 {
+		return false
+	}
+
+	s.stopClocks()
+
+	s.Status = GameTimeout
+	s.Winner = switchPlayer(s.Turn)
+
+	return true
+}
+
+// movesToGo is how many moves engine expects to make with its remaining time
+func (s *Session) movesToGo() time.Duration {
+	moves := s.Board.NumFreeCells() / 2
+	if moves < 5 {
+		moves = 5
+	} else if moves > 30 {
+		moves = 30
+	}
+	return time.Duration(moves)
+}
+
+// thinkingTime returns time engine playing side can spend on its move, it is
+// zero if moves are not timed
+func (s *Session) thinkingTime(side Cell) time.Duration {
+
+	left := s.TimeLeft(side)
+
+	switch s.TimeControl.Kind {
+
+	case SuddenDeath:
+		return left / s.movesToGo()
+
+	case Fischer:
+		// most of increment can be spent while there is enough time left
+		t := left/s.movesToGo() + s.TimeControl.Increment*3/4
+		if t > left/2 {
+			t = left / 2
+		}
+		return t
+
+	case FixedPerMove:
+		// some time is kept for making the move itself
+		return left * 8 / 10
+	}
+
+	return 0
+}
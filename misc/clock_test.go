@@ -0,0 +1,184 @@
+package misc
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// fakeClock replaces time source of clocks, returns a function advancing time
+// and a function restoring the real time source
+func fakeClock() (advance func(time.Duration), restore func()) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	return func(d time.Duration) { now = now.Add(d) }, func() { timeNow = time.Now }
+}
+
+func timedSession(tc TimeControl) *Session {
+	return NewSession(SessionOptions{BoardSide: 7, WinLength: 4, Rules: Freestyle,
+		PlayerX: HumanPlayer("alice"), PlayerO: HumanPlayer("bob"), TimeControl: tc})
+}
+
+func TestParseTimeControl(t *testing.T) {
+
+	for s, expected := range map[string]TimeControl{
+		"":         {},
+		"none":     {},
+		"5m":       {SuddenDeath, 5 * time.Minute, 0},
+		"5m+3s":    {Fischer, 5 * time.Minute, 3 * time.Second},
+		"10s/move": {FixedPerMove, 10 * time.Second, 0},
+	} {
+		tc, err := ParseTimeControl(s)
+		assertNoError(t, err)
+		assertEqual(t, tc, expected)
+
+		// formatted time control is read back the same
+		parsed, err := ParseTimeControl(tc.String())
+		assertNoError(t, err)
+		assertEqual(t, parsed, tc)
+	}
+
+	for _, s := range []string{"5", "-5m", "5m+", "5m+x", "0s/move", "move"} {
+		if _, err := ParseTimeControl(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestClockSuddenDeath(t *testing.T) {
+
+	advance, restore := fakeClock()
+	defer restore()
+
+	s := timedSession(TimeControl{Kind: SuddenDeath, Main: time.Minute})
+
+	assertEqual(t, s.ClockX.Running(), true)
+	assertEqual(t, s.ClockO.Running(), false)
+
+	advance(10 * time.Second)
+	assertEqual(t, s.TimeLeft(X), 50*time.Second)
+
+	assertNoError(t, s.Play(CellPosition{0, 0}))
+	assertEqual(t, s.ClockX.Running(), false)
+	assertEqual(t, s.ClockO.Running(), true)
+
+	advance(20 * time.Second)
+	assertEqual(t, s.TimeLeft(X), 50*time.Second)
+	assertEqual(t, s.TimeLeft(O), 40*time.Second)
+
+	// the flag has not fallen yet
+	assertEqual(t, s.CheckTime(), false)
+
+	advance(40 * time.Second)
+	assertEqual(t, s.TimeLeft(O), time.Duration(0))
+
+	// a late move loses on time
+	assertEqual(t, s.Play(CellPosition{1, 1}), error(ErrGameOver))
+	assertEqual(t, s.Status, GameTimeout)
+	assertEqual(t, s.Winner, Cell(X))
+	assertEqual(t, s.ClockO.Running(), false)
+	assertEqual(t, s.CheckTime(), false)
+}
+
+func TestClockFischer(t *testing.T) {
+
+	advance, restore := fakeClock()
+	defer restore()
+
+	s := timedSession(TimeControl{Kind: Fischer, Main: time.Minute, Increment: 5 * time.Second})
+
+	advance(2 * time.Second)
+	assertNoError(t, s.Play(CellPosition{0, 0}))
+	assertEqual(t, s.TimeLeft(X), 63*time.Second)
+
+	advance(30 * time.Second)
+	assertNoError(t, s.Play(CellPosition{1, 1}))
+	assertEqual(t, s.TimeLeft(O), 35*time.Second)
+
+	advance(time.Minute + 3*time.Second)
+	assertEqual(t, s.CheckTime(), true)
+	assertEqual(t, s.Status, GameTimeout)
+	assertEqual(t, s.Winner, Cell(O))
+}
+
+func TestClockFixedPerMove(t *testing.T) {
+
+	advance, restore := fakeClock()
+	defer restore()
+
+	s := timedSession(TimeControl{Kind: FixedPerMove, Main: 10 * time.Second})
+
+	advance(9 * time.Second)
+	assertNoError(t, s.Play(CellPosition{0, 0}))
+
+	// unused time is not kept
+	assertEqual(t, s.TimeLeft(X), 10*time.Second)
+
+	advance(3 * time.Second)
+	assertNoError(t, s.Undo())
+	assertEqual(t, s.ClockX.Running(), true)
+	assertEqual(t, s.ClockO.Running(), false)
+	assertEqual(t, s.TimeLeft(O), 7*time.Second)
+
+	assertNoError(t, s.Resign(X))
+	assertEqual(t, s.ClockX.Running(), false)
+}
+
+func TestClockEngineTime(t *testing.T) {
+
+	advance, restore := fakeClock()
+	defer restore()
+
+	s := timedSession(TimeControl{Kind: SuddenDeath, Main: time.Minute})
+
+	// 49 free cells give 24 moves to go
+	assertEqual(t, s.thinkingTime(X), time.Minute/24)
+
+	advance(50 * time.Second)
+	assertEqual(t, s.thinkingTime(X), 10*time.Second/24)
+
+	s.TimeControl = TimeControl{Kind: Fischer, Main: time.Minute, Increment: 4 * time.Second}
+	assertEqual(t, s.thinkingTime(X), 10*time.Second/24+3*time.Second)
+
+	// engine never spends more than a half of its time
+	advance(9 * time.Second)
+	assertEqual(t, s.thinkingTime(X), 500*time.Millisecond)
+
+	s.TimeControl = TimeControl{}
+	assertEqual(t, s.thinkingTime(X), time.Duration(0))
+}
+
+func TestClockSaveLoad(t *testing.T) {
+
+	advance, restore := fakeClock()
+	defer restore()
+
+	s := timedSession(TimeControl{Kind: Fischer, Main: time.Minute, Increment: time.Second})
+
+	advance(15 * time.Second)
+	assertNoError(t, s.Play(CellPosition{3, 3}))
+	advance(5 * time.Second)
+
+	var buf bytes.Buffer
+	assertNoError(t, s.Save(&buf))
+
+	loaded, err := LoadSession(&buf)
+	assertNoError(t, err)
+
+	assertEqual(t, loaded.TimeControl, s.TimeControl)
+	assertEqual(t, loaded.TimeLeft(X), 46*time.Second)
+	assertEqual(t, loaded.TimeLeft(O), 55*time.Second)
+	assertEqual(t, loaded.ClockO.Running(), true)
+
+	// game lost on time is restored as well
+	advance(time.Minute)
+	assertEqual(t, s.CheckTime(), true)
+
+	buf.Reset()
+	assertNoError(t, s.Save(&buf))
+
+	loaded, err = LoadSession(&buf)
+	assertNoError(t, err)
+	assertEqual(t, loaded.Status, GameTimeout)
+	assertEqual(t, loaded.Winner, Cell(X))
+}
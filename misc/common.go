@@ -72,6 +72,13 @@ func assertEqual(t *testing.T, x, y interface{}) {
 	}
 }
 
+func assertNoError(t *testing.T, err error) {
+	pc, _, _, _ := runtime.Caller(1)
+	if err != nil {
+		t.Fatalf("Unexpected error in %v: %v", runtime.FuncForPC(pc).Name(), err)
+	}
+}
+
 func minIntPair(a, b int) int {
 	return int(math.Min(float64(a), float64(b)))
 }
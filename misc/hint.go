@@ -0,0 +1,114 @@
+package misc
+
+import "errors"
+
+// ErrStopped is returned by analysis which is stopped before it is done
+var ErrStopped = errors.New("analysis is stopped")
+
+// Candidate is a cell rated by Monte-Carlo evaluation, better cells have higher scores
+type Candidate struct {
+	Pos   CellPosition
+	Score float64
+}
+
+// Hint is a move suggested by engine to the side to move
+type Hint struct {
+	Side       Cell
+	Best       CellPosition
+	Candidates []Candidate
+}
+
+// hintTrials is the number of Monte-Carlo trials used to rate hint candidates
+const hintTrials = 500
+
+// Hint asks engine which move the side to move should make and rates up to n
+// the most promising cells, the move is not made, engine settings are taken
+// from the opponent if it is an engine, search is cut short when stop is closed
+func (s *Session) Hint(n int, stop <-chan struct{}) (Hint, error) {
+
+	if s.Over() {
+		return Hint{}, ErrGameOver
+	}
+
+	options := s.Player(s.Turn).AI
+
+	if opponent := s.Player(switchPlayer(s.Turn)); opponent.Kind == Engine {
+		options = NewAIOptions(s.Turn, s.WinLength, opponent.AI.maxDepth)
+		options.TimeLimit = opponent.AI.TimeLimit
+	}
+
+	options = s.searchOptions(options, stop)
+
+	best, err := s.engineMove(options, nil)
+	if err != nil {
+		return Hint{}, err
+	}
+
+	hint := Hint{Side: s.Turn, Best: best}
+
+	for _, move := range RankMoves(s.Board, options, hintTrials) {
+
+		if len(hint.Candidates) >= n {
+			break
+		}
+
+		col, row, _ := s.Board.FromLinear(move.Fst)
+		if pos := (CellPosition{col, row}); s.checkMove(s.Turn, pos) == nil {
+			hint.Candidates = append(hint.Candidates, Candidate{pos, move.Snd})
+		}
+	}
+
+	return hint, nil
+}
+
+// Heatmap rates each cell of the board for the side to move, scores are
+// indexed as board cells, occupied cells are rated zero
+type Heatmap struct {
+	Side   Cell
+	Scores []float64
+
+	// the lowest and the highest scores of free cells
+	Min, Max float64
+}
+
+// Score returns the score of a cell at pos
+func (h Heatmap) Score(board *BoardDescription, pos CellPosition) float64 {
+	idx, err := board.ToLinear(pos.Col, pos.Row)
+	if err != nil || idx >= len(h.Scores) {
+		return 0
+	}
+	return h.Scores[idx]
+}
+
+// Heatmap rates free cells by Monte-Carlo evaluation for the side to move,
+// session is not changed, ErrStopped is returned if stop is closed meanwhile
+func (s *Session) Heatmap(stop <-chan struct{}) (Heatmap, error) {
+
+	if s.Over() {
+		return Heatmap{}, ErrGameOver
+	}
+
+	options := s.Player(s.Turn).AI
+	options.stop = stop
+
+	heatmap := Heatmap{Side: s.Turn, Scores: EvalCells(s.Board, options, hintTrials)}
+
+	// scores of a stopped evaluation are made of a part of trials only
+	if options.timeIsUp() {
+		return Heatmap{}, ErrStopped
+	}
+
+	first := true
+	for _, idx := range s.Board.GetFreeIndices() {
+		score := heatmap.Scores[idx]
+		if first || score < heatmap.Min {
+			heatmap.Min = score
+		}
+		if first || score > heatmap.Max {
+			heatmap.Max = score
+		}
+		first = false
+	}
+
+	return heatmap, nil
+}
@@ -0,0 +1,76 @@
+package misc
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestHint(t *testing.T) {
+
+	// hint searches as deep as the opponent engine
+	session := NewSession(SessionOptions{BoardSide: 6, WinLength: 4, Rules: Freestyle,
+		PlayerX: HumanPlayer("Human"), PlayerO: EnginePlayer("Goblin", 2)})
+
+	for i := 0; i < 3; i++ {
+		assertNoError(t, session.Play(CellPosition{i, 0}))
+		assertNoError(t, session.place(O, CellPosition{i, 2}))
+	}
+
+	hint, err := session.Hint(3, nil)
+	assertNoError(t, err)
+
+	// X wins at once
+	assertEqual(t, hint.Side, Cell(X))
+	assertEqual(t, hint.Best, CellPosition{3, 0})
+
+	assertEqual(t, len(hint.Candidates), 3)
+	assertEqual(t, sort.SliceIsSorted(hint.Candidates, func(i, j int) bool {
+		return hint.Candidates[i].Score > hint.Candidates[j].Score
+	}), true)
+
+	for _, c := range hint.Candidates {
+		assertEqual(t, session.Board.GetCell(c.Pos.Col, c.Pos.Row), Cell(E))
+	}
+
+	// nothing is played
+	assertEqual(t, len(session.History), 6)
+	assertEqual(t, session.Turn, Cell(X))
+
+	assertNoError(t, session.Play(hint.Best))
+
+	_, err = session.Hint(3, nil)
+	assertEqual(t, err, error(ErrGameOver))
+}
+
+func TestHeatmap(t *testing.T) {
+
+	session := CreateNewSession(6, 4, X)
+
+	assertNoError(t, session.Play(CellPosition{2, 2}))
+	assertNoError(t, session.place(O, CellPosition{3, 3}))
+
+	heatmap, err := session.Heatmap(nil)
+	assertNoError(t, err)
+
+	assertEqual(t, heatmap.Side, Cell(X))
+	assertEqual(t, len(heatmap.Scores), 36)
+
+	// occupied cells are not rated
+	assertEqual(t, heatmap.Score(session.Board, CellPosition{2, 2}), 0.0)
+	assertEqual(t, heatmap.Score(session.Board, CellPosition{3, 3}), 0.0)
+
+	for _, idx := range session.Board.GetFreeIndices() {
+		if score := heatmap.Scores[idx]; score < heatmap.Min || score > heatmap.Max {
+			t.Fatalf("score %v of cell %d is out of range %v..%v", score, idx, heatmap.Min, heatmap.Max)
+		}
+	}
+
+	assertEqual(t, heatmap.Score(session.Board, CellPosition{6, 0}), 0.0)
+
+	// engine search cancels analysis
+	stop := make(chan struct{})
+	close(stop)
+
+	_, err = session.Heatmap(stop)
+	assertEqual(t, err, error(ErrStopped))
+}
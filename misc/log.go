@@ -0,0 +1,16 @@
+package misc
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+)
+
+// engine log is discarded unless an output is set, writing to stdout or stderr
+// would break terminal UI and text protocols
+var logger = log.New(ioutil.Discard, "goblin: ", log.LstdFlags)
+
+// SetLogOutput sets destination for engine log
+func SetLogOutput(w io.Writer) {
+	logger.SetOutput(w)
+}
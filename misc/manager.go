@@ -1,6 +1,9 @@
 package misc
 
 import (
+	"errors"
+	"sort"
+	"sync"
 	"time"
 	"math/rand"
 )
@@ -10,10 +13,25 @@ import (
 
 type Session struct {
 	Board      *BoardDescription
-	AI         AIOptions
+	PlayerX    Player
+	PlayerO    Player
+	Rules      Rules
+	WinLength  int
 	SessionID  string
+
+	// side to move and game result
+	Turn       Cell
+	Status     GameStatus
 	Winner     Cell
 	Intervals  []Interval
+
+	// all moves made so far in order
+	History    []Move
+
+	// players' clocks, they are used only if TimeControl is set
+	TimeControl TimeControl
+	ClockX      Clock
+	ClockO      Clock
 }
 
 
@@ -24,13 +42,21 @@ const (
 	letterIdxMax  = 63 / letterIdxBits   // # of letter indices fitting in 63 bits
 )
 
+// idSource generates session IDs, it is seeded once and shared by all sessions
+var idSource = struct {
+	sync.Mutex
+	rand.Source
+}{Source: rand.NewSource(time.Now().UnixNano())}
+
 // generate random ID n symbols length, taken from
 // http://stackoverflow.com/questions/22892120/how-to-generate-a-random-string-of-a-fixed-length-in-golang
 func generateSessionId(n int) string {
 
-	var src = rand.NewSource(time.Now().UnixNano())
+	idSource.Lock()
+	defer idSource.Unlock()
+
+	src := idSource.Source
 
-	// session id will be just a time it was created
 	b := make([]byte, n)
 
 	// A src.Int63() generates 63 random bits, enough for letterIdxMax characters!
@@ -49,30 +75,257 @@ func generateSessionId(n int) string {
 	return string(b)
 }
 
+// SessionOptions describes a game to be started by NewSession
+type SessionOptions struct {
+	BoardSide int
+	WinLength int
+	Rules     Rules
+
+	PlayerX Player
+	PlayerO Player
+
+	// side which makes the first move, X if not set
+	FirstMove Cell
+
+	// time given to players, moves are not timed if it is not set
+	TimeControl TimeControl
+}
+
+// NewSession starts a new game, engine settings of both players are
+// completed with their sides and win length of the game
+func NewSession(options SessionOptions) *Session {
+
+	if options.FirstMove != O {
+		options.FirstMove = X
+	}
+
+	session := &Session{
+		Board: NewBoard(options.BoardSide, options.BoardSide),
+
+		PlayerX: options.PlayerX,
+		PlayerO: options.PlayerO,
+
+		Rules: options.Rules,
+		WinLength: options.WinLength,
+		SessionID: generateSessionId(10),
+
+		Turn: options.FirstMove,
+		Status: GameInProgress,
+		Winner: E,
+		Intervals: []Interval{},
+
+		TimeControl: options.TimeControl,
+	}
+
+	for _, side := range []Cell{X, O} {
+		player := session.Player(side)
+		timeLimit := player.AI.TimeLimit
+		player.AI = NewAIOptions(side, options.WinLength, player.AI.maxDepth)
+		player.AI.TimeLimit = timeLimit
+	}
+
+	session.resetClocks()
+
+	return session
+}
+
+// CreateNewSession starts a game of human playing for player against engine
 func CreateNewSession(boardSide, winSeqLen int, player Cell) Session {
 
-	rand.Seed(time.Now().UTC().UnixNano())
+	options := SessionOptions{BoardSide: boardSide, WinLength: winSeqLen, Rules: Freestyle}
+
+	if player == X {
+		options.PlayerX, options.PlayerO = HumanPlayer("Human"), EnginePlayer("Goblin", DefaultDepth)
+	} else {
+		options.PlayerX, options.PlayerO = EnginePlayer("Goblin", DefaultDepth), HumanPlayer("Human")
+	}
+
+	return *NewSession(options)
+}
+
+// Errors returned by Manager
+var (
+	ErrNoSession       = errors.New("session not found")
+	ErrTooManySessions = errors.New("too many sessions")
+)
+
+// managedSession is a session kept by Manager, it is guarded by its own mutex
+// so that games in different sessions don't block each other
+type managedSession struct {
+	sync.Mutex
+	session *Session
+
+	// guarded by Manager.mu
+	lastUsed time.Time
+	users    int
+}
+
+// Manager keeps sessions by their IDs, it is safe for concurrent use
+type Manager struct {
+	mu       sync.Mutex
+	sessions map[string]*managedSession
+
+	// sessions unused for longer than IdleTimeout are removed by Expire,
+	// zero means sessions never expire
+	IdleTimeout time.Duration
+
+	// maximal number of sessions kept at once, zero means no limit
+	MaxSessions int
+}
 
-	return Session{
-		NewBoard(boardSide, boardSide),
+// SessionInfo describes a session kept by Manager
+type SessionInfo struct {
+	ID       string
+	LastUsed time.Time
+}
 
-		AIOptions{switchPlayer(player),
-			  winSeqLen,
-		          5,
-			  true},
+// NewManager creates an empty sessions manager
+func NewManager(idleTimeout time.Duration) *Manager {
+	return &Manager{sessions: make(map[string]*managedSession), IdleTimeout: idleTimeout}
+}
 
-		generateSessionId(10),
-		E,
-		[]Interval{},
+// Create starts a new session and returns its ID, idle sessions are expired
+// if the limit of sessions is reached
+func (m *Manager) Create(options SessionOptions) (string, error) {
+
+	session := NewSession(options)
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.MaxSessions > 0 && len(m.sessions) >= m.MaxSessions {
+		if m.expire(now) == 0 || len(m.sessions) >= m.MaxSessions {
+			return "", ErrTooManySessions
+		}
 	}
+
+	// IDs are random, so a collision is unlikely but still possible
+	for {
+		if _, found := m.sessions[session.SessionID]; !found {
+			break
+		}
+		session.SessionID = generateSessionId(len(session.SessionID))
+	}
+
+	m.sessions[session.SessionID] = &managedSession{session: session, lastUsed: now}
+
+	return session.SessionID, nil
+}
+
+// With calls fn for a session with a given ID, fn has exclusive access to the session
+// and must not keep it after return
+func (m *Manager) With(id string, fn func(s *Session) error) error {
+
+	m.mu.Lock()
+	entry, found := m.sessions[id]
+	if found {
+		entry.users++
+	}
+	m.mu.Unlock()
+
+	if !found {
+		return ErrNoSession
+	}
+
+	defer func() {
+		m.mu.Lock()
+		entry.users--
+		entry.lastUsed = time.Now()
+		m.mu.Unlock()
+	}()
+
+	entry.Lock()
+	defer entry.Unlock()
+
+	return fn(entry.session)
 }
 
-func RemoveSession() {
+// List returns sessions sorted by ID
+func (m *Manager) List() []SessionInfo {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]SessionInfo, 0, len(m.sessions))
+	for id, entry := range m.sessions {
+		list = append(list, SessionInfo{id, entry.lastUsed})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
 
+	return list
 }
 
-func (s *Session) MakeMove() {
-	winner, intervals := MakeMove(s.Board, s.AI)
-	s.Winner = winner
-	s.Intervals = intervals
-}
\ No newline at end of file
+// Len returns the number of sessions
+func (m *Manager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sessions)
+}
+
+// Remove deletes a session with a given ID, a call to With running at the
+// same time completes normally
+func (m *Manager) Remove(id string) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.sessions[id]; !found {
+		return ErrNoSession
+	}
+
+	delete(m.sessions, id)
+
+	return nil
+}
+
+// Expire removes sessions which have been idle for longer than IdleTimeout
+// and returns the number of removed sessions, sessions in use are never removed
+func (m *Manager) Expire(now time.Time) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.expire(now)
+}
+
+func (m *Manager) expire(now time.Time) int {
+
+	if m.IdleTimeout <= 0 {
+		return 0
+	}
+
+	removed := 0
+
+	for id, entry := range m.sessions {
+		if entry.users == 0 && now.Sub(entry.lastUsed) > m.IdleTimeout {
+			delete(m.sessions, id)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// ExpireEvery calls Expire periodically in background until returned stop
+// function is called
+func (m *Manager) ExpireEvery(interval time.Duration) (stop func()) {
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case now := <-ticker.C:
+				m.Expire(now)
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -0,0 +1,153 @@
+package misc
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func testSessionOptions() SessionOptions {
+	return SessionOptions{BoardSide: 5, WinLength: 3, Rules: Freestyle,
+		PlayerX: HumanPlayer("First"), PlayerO: HumanPlayer("Second")}
+}
+
+func TestManagerUniqueIDs(t *testing.T) {
+
+	m := NewManager(0)
+
+	const workers, perWorker = 8, 100
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := m.Create(testSessionOptions()); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// sessions with equal IDs would overwrite each other
+	assertEqual(t, m.Len(), workers*perWorker)
+
+	list := m.List()
+	assertEqual(t, len(list), workers*perWorker)
+	for i := 1; i < len(list); i++ {
+		if list[i-1].ID >= list[i].ID {
+			t.Fatalf("list is not sorted or has duplicates: %q, %q", list[i-1].ID, list[i].ID)
+		}
+	}
+}
+
+func TestManagerConcurrentMoves(t *testing.T) {
+
+	m := NewManager(time.Hour)
+
+	id, err := m.Create(testSessionOptions())
+	assertNoError(t, err)
+
+	// every goroutine tries to fill the whole board, each cell is taken only once
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for col := 0; col < 5; col++ {
+				for row := 0; row < 5; row++ {
+					m.With(id, func(s *Session) error {
+						return s.Play(CellPosition{col, row})
+					})
+					m.List()
+					m.Expire(time.Now())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	m.With(id, func(s *Session) error {
+		seen := map[CellPosition]bool{}
+		for _, move := range s.History {
+			if seen[move.Pos] {
+				t.Errorf("cell %v is taken twice", move.Pos)
+			}
+			seen[move.Pos] = true
+		}
+		return nil
+	})
+}
+
+func TestManagerRemove(t *testing.T) {
+
+	m := NewManager(0)
+
+	id, err := m.Create(testSessionOptions())
+	assertNoError(t, err)
+
+	assertNoError(t, m.Remove(id))
+	assertEqual(t, m.Remove(id), ErrNoSession)
+	assertEqual(t, m.With(id, func(s *Session) error { return nil }), ErrNoSession)
+}
+
+func TestManagerExpire(t *testing.T) {
+
+	m := NewManager(time.Minute)
+
+	idle, _ := m.Create(testSessionOptions())
+	busy, _ := m.Create(testSessionOptions())
+
+	later := time.Now().Add(2 * time.Minute)
+
+	// a session in use is kept even if it has been idle before
+	m.With(busy, func(s *Session) error {
+		assertEqual(t, m.Expire(later), 1)
+		return nil
+	})
+
+	assertEqual(t, m.With(idle, func(s *Session) error { return nil }), ErrNoSession)
+	assertEqual(t, m.Len(), 1)
+
+	// busy session has just been used
+	assertEqual(t, m.Expire(time.Now().Add(30*time.Second)), 0)
+	assertEqual(t, m.Expire(time.Now().Add(2*time.Minute)), 1)
+}
+
+func TestManagerExpireEvery(t *testing.T) {
+
+	m := NewManager(time.Millisecond)
+	m.Create(testSessionOptions())
+
+	stop := m.ExpireEvery(time.Millisecond)
+	defer stop()
+
+	for deadline := time.Now().Add(time.Second); m.Len() != 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("session has not expired")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestManagerMaxSessions(t *testing.T) {
+
+	m := NewManager(time.Minute)
+	m.MaxSessions = 2
+
+	m.Create(testSessionOptions())
+	m.Create(testSessionOptions())
+
+	_, err := m.Create(testSessionOptions())
+	assertEqual(t, err, ErrTooManySessions)
+
+	// idle sessions give way to new ones
+	m.IdleTimeout = time.Nanosecond
+	time.Sleep(time.Millisecond)
+
+	_, err = m.Create(testSessionOptions())
+	assertNoError(t, err)
+	assertEqual(t, m.Len(), 1)
+}
@@ -0,0 +1,116 @@
+package misc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Standard coordinate notation
+//
+// A cell is written as a column name followed by a row number, e.g. "H8".
+// Columns are named with letters, after "Z" come "AA", "AB" and so on, rows
+// are numbered from 1.
+
+// Origin defines the corner of a board where numbering starts
+type Origin uint8
+
+const (
+	// TopLeft numbers rows from top to bottom, this is how the board is labeled on screen
+	TopLeft Origin = iota
+	// BottomLeft numbers rows from bottom to top like in chess or GTP
+	BottomLeft
+)
+
+const (
+	allLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	lettersNoI = "ABCDEFGHJKLMNOPQRSTUVWXYZ"
+)
+
+// Notation converts cell positions into text and back
+type Notation struct {
+	// number of rows on a board, only required for BottomLeft origin
+	Rows int
+
+	Origin Origin
+
+	// SkipI excludes letter I from column names as GTP does
+	SkipI bool
+}
+
+// DefaultNotation returns notation used across goblin for a board with a given number of rows
+func DefaultNotation(rows int) Notation {
+	return Notation{Rows: rows, Origin: TopLeft}
+}
+
+func (n Notation) letters() string {
+	if n.SkipI {
+		return lettersNoI
+	}
+	return allLetters
+}
+
+// ColumnName returns name of a column with index col
+func (n Notation) ColumnName(col int) string {
+
+	letters := n.letters()
+	base := len(letters)
+
+	name := []byte{}
+	for col++; col > 0; col = (col - 1) / base {
+		name = append([]byte{letters[(col-1)%base]}, name...)
+	}
+
+	return string(name)
+}
+
+// RowName returns number of a row with index row
+func (n Notation) RowName(row int) string {
+	if n.Origin == BottomLeft {
+		row = n.Rows - 1 - row
+	}
+	return strconv.Itoa(row + 1)
+}
+
+// Format returns notation of a cell
+func (n Notation) Format(pos CellPosition) string {
+	return n.ColumnName(pos.Col) + n.RowName(pos.Row)
+}
+
+// Parse converts notation like "H8" or "aa12" into a cell position, only the
+// lower bound is checked so the caller should make sure the cell is on a board
+func (n Notation) Parse(s string) (CellPosition, error) {
+
+	letters := n.letters()
+	base := len(letters)
+
+	upper := strings.ToUpper(strings.TrimSpace(s))
+
+	col, i := 0, 0
+	for ; i < len(upper); i++ {
+		idx := strings.IndexByte(letters, upper[i])
+		if idx < 0 {
+			break
+		}
+		col = col*base + idx + 1
+	}
+
+	if i == 0 || i == len(upper) {
+		return CellPosition{}, fmt.Errorf("invalid cell %q", s)
+	}
+
+	row, err := strconv.Atoi(upper[i:])
+	if err != nil || row < 1 || upper[i] == '+' {
+		return CellPosition{}, fmt.Errorf("invalid cell %q", s)
+	}
+
+	row--
+	if n.Origin == BottomLeft {
+		if row >= n.Rows {
+			return CellPosition{}, fmt.Errorf("invalid cell %q", s)
+		}
+		row = n.Rows - 1 - row
+	}
+
+	return CellPosition{col - 1, row}, nil
+}
@@ -0,0 +1,76 @@
+package misc
+
+import (
+	"testing"
+)
+
+func TestNotationFormat(t *testing.T) {
+
+	notation := DefaultNotation(15)
+
+	assertEqual(t, notation.Format(CellPosition{0, 0}), "A1")
+	assertEqual(t, notation.Format(CellPosition{7, 7}), "H8")
+	assertEqual(t, notation.Format(CellPosition{8, 14}), "I15")
+
+	assertEqual(t, notation.ColumnName(25), "Z")
+	assertEqual(t, notation.ColumnName(26), "AA")
+	assertEqual(t, notation.ColumnName(27), "AB")
+	assertEqual(t, notation.ColumnName(52), "BA")
+	assertEqual(t, notation.ColumnName(701), "ZZ")
+	assertEqual(t, notation.ColumnName(702), "AAA")
+
+	gtp := Notation{Rows: 19, Origin: BottomLeft, SkipI: true}
+
+	assertEqual(t, gtp.Format(CellPosition{0, 18}), "A1")
+	assertEqual(t, gtp.Format(CellPosition{8, 0}), "J19")
+	assertEqual(t, gtp.ColumnName(24), "Z")
+	assertEqual(t, gtp.ColumnName(25), "AA")
+}
+
+func TestNotationParse(t *testing.T) {
+
+	notations := []Notation{
+		DefaultNotation(30),
+		{Rows: 30, Origin: BottomLeft},
+		{Rows: 30, Origin: TopLeft, SkipI: true},
+		{Rows: 30, Origin: BottomLeft, SkipI: true},
+	}
+
+	for _, notation := range notations {
+		for col := 0; col < 800; col++ {
+			for row := 0; row < 30; row++ {
+				pos := CellPosition{col, row}
+				parsed, err := notation.Parse(notation.Format(pos))
+				assertNoError(t, err)
+				assertEqual(t, parsed, pos)
+			}
+		}
+	}
+
+	pos, err := DefaultNotation(15).Parse(" h8 ")
+	assertNoError(t, err)
+	assertEqual(t, pos, CellPosition{7, 7})
+
+	pos, err = Notation{Rows: 15, Origin: BottomLeft}.Parse("H8")
+	assertNoError(t, err)
+	assertEqual(t, pos, CellPosition{7, 7})
+
+	pos, err = Notation{Rows: 15, Origin: BottomLeft}.Parse("a1")
+	assertNoError(t, err)
+	assertEqual(t, pos, CellPosition{0, 14})
+
+	invalid := []string{"", "H", "8", "H0", "H-1", "H+1", "8H", "H8X", "[1", "A 1"}
+
+	for _, s := range invalid {
+		if _, err := DefaultNotation(15).Parse(s); err == nil {
+			t.Fatalf("Invalid cell %q has been parsed", s)
+		}
+	}
+
+	// out of the board for bottom-left origin, I is not allowed when skipped
+	for _, s := range []string{"A16", "I5"} {
+		if _, err := (Notation{Rows: 15, Origin: BottomLeft, SkipI: true}).Parse(s); err == nil {
+			t.Fatalf("Invalid cell %q has been parsed", s)
+		}
+	}
+}
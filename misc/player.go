@@ -0,0 +1,56 @@
+package misc
+
+// PlayerKind tells who is in charge of a side
+type PlayerKind uint8
+
+const (
+	// Human moves are passed to Session.Play
+	Human PlayerKind = iota
+	// Engine moves are made by Session.MakeMove
+	Engine
+)
+
+func (k PlayerKind) String() string {
+	if k == Engine {
+		return "engine"
+	}
+	return "human"
+}
+
+// DefaultDepth is the search depth used when engine settings leave it unset
+const DefaultDepth = 5
+
+// Player describes one side of a session
+type Player struct {
+	Kind PlayerKind
+	Name string
+
+	// engine settings, ignored for human players
+	AI AIOptions
+}
+
+// NewAIOptions creates engine settings for a given side, win length and search depth
+func NewAIOptions(aiPlayer Cell, winSeqLen, maxDepth int) AIOptions {
+	if maxDepth <= 0 {
+		maxDepth = DefaultDepth
+	}
+	return AIOptions{AIPlayer: aiPlayer,
+		winSequenceLength: winSeqLen,
+		maxDepth:          maxDepth,
+		useGoRoutines:     true}
+}
+
+// HumanPlayer returns a human player with a given name
+func HumanPlayer(name string) Player {
+	return Player{Kind: Human, Name: name}
+}
+
+// EnginePlayer returns an engine player searching maxDepth moves ahead
+func EnginePlayer(name string, maxDepth int) Player {
+	return Player{Kind: Engine, Name: name, AI: AIOptions{maxDepth: maxDepth}}
+}
+
+// MaxDepth returns search depth of engine settings
+func (o AIOptions) MaxDepth() int {
+	return o.maxDepth
+}
@@ -0,0 +1,203 @@
+package misc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Piskvork (Gomocup) game records support
+//
+// PSQ file starts with a header like "Piskvorky 20x20, 11:11, 0" followed by
+// moves, one per line, in "x,y,time" format where x and y are 1-based column
+// and row and time is a number of milliseconds spent on a move. Any lines after
+// the moves (names of brains, "-1" terminators, etc.) are kept as is.
+
+// Values of the result field of a PSQ header as written by goblin
+const (
+	PSQUnfinished = iota
+	PSQFirstWon
+	PSQSecondWon
+	PSQDraw
+)
+
+// PSQMove is a single move of a PSQ record
+type PSQMove struct {
+	Pos  CellPosition
+	Time int
+}
+
+// PSQRecord is a game stored in Piskvork format
+type PSQRecord struct {
+	Width, Height int
+
+	// two numbers following board size in the header
+	Score [2]int
+
+	// the last field of the header
+	Result int
+
+	Moves []PSQMove
+
+	// lines following the moves
+	Trailer []string
+}
+
+// parsePSQHeader parses header line "Piskvorky WxH, A:B, R"
+func parsePSQHeader(line string, rec *PSQRecord) error {
+
+	if !strings.HasPrefix(line, "Piskvorky ") {
+		return fmt.Errorf("line 1: not a Piskvork record")
+	}
+
+	fields := strings.Split(strings.TrimPrefix(line, "Piskvorky "), ",")
+	if len(fields) != 3 {
+		return fmt.Errorf("line 1: malformed header %q", line)
+	}
+
+	_, err := fmt.Sscanf(strings.TrimSpace(fields[0]), "%dx%d", &rec.Width, &rec.Height)
+	if err != nil || rec.Width <= 0 || rec.Height <= 0 || rec.Width > MaxBoardSide || rec.Height > MaxBoardSide {
+		return fmt.Errorf("line 1: malformed board size %q", fields[0])
+	}
+
+	_, err = fmt.Sscanf(strings.TrimSpace(fields[1]), "%d:%d", &rec.Score[0], &rec.Score[1])
+	if err != nil {
+		return fmt.Errorf("line 1: malformed score %q", fields[1])
+	}
+
+	if rec.Result, err = strconv.Atoi(strings.TrimSpace(fields[2])); err != nil {
+		return fmt.Errorf("line 1: malformed result %q", fields[2])
+	}
+
+	return nil
+}
+
+// parsePSQMove parses "x,y,time" move line, returns false if the line is not a move
+func parsePSQMove(line string) (PSQMove, bool) {
+
+	fields := strings.Split(line, ",")
+	if len(fields) != 3 {
+		return PSQMove{}, false
+	}
+
+	values := make([]int, len(fields))
+	for i, field := range fields {
+		value, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return PSQMove{}, false
+		}
+		values[i] = value
+	}
+
+	return PSQMove{CellPosition{values[0] - 1, values[1] - 1}, values[2]}, true
+}
+
+// ReadPSQ reads a game record in Piskvork format
+func ReadPSQ(r io.Reader) (*PSQRecord, error) {
+
+	rec := &PSQRecord{}
+	scanner := bufio.NewScanner(r)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("line 1: empty record")
+	}
+
+	if err := parsePSQHeader(strings.TrimSpace(scanner.Text()), rec); err != nil {
+		return nil, err
+	}
+
+	movesDone := false
+
+	for lineNo := 2; scanner.Scan(); lineNo++ {
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if !movesDone {
+			if move, ok := parsePSQMove(line); ok {
+				if move.Pos.Col < 0 || move.Pos.Row < 0 || move.Pos.Col >= rec.Width || move.Pos.Row >= rec.Height {
+					return nil, fmt.Errorf("line %d: move %q is out of the board", lineNo, line)
+				}
+				rec.Moves = append(rec.Moves, move)
+				continue
+			}
+			movesDone = true
+		}
+
+		rec.Trailer = append(rec.Trailer, line)
+	}
+
+	// drop trailing empty lines
+	for len(rec.Trailer) > 0 && rec.Trailer[len(rec.Trailer)-1] == "" {
+		rec.Trailer = rec.Trailer[:len(rec.Trailer)-1]
+	}
+
+	return rec, scanner.Err()
+}
+
+// Write writes a record in Piskvork format
+func (rec *PSQRecord) Write(w io.Writer) error {
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "Piskvorky %dx%d, %d:%d, %d\n", rec.Width, rec.Height, rec.Score[0], rec.Score[1], rec.Result)
+
+	for _, move := range rec.Moves {
+		fmt.Fprintf(bw, "%d,%d,%d\n", move.Pos.Col+1, move.Pos.Row+1, move.Time)
+	}
+
+	for _, line := range rec.Trailer {
+		fmt.Fprintln(bw, line)
+	}
+
+	return bw.Flush()
+}
+
+// Session replays record moves in a new human vs human session, the first
+// move is made by X
+func (rec *PSQRecord) Session(winLength int) (*Session, error) {
+
+	if rec.Width != rec.Height {
+		return nil, fmt.Errorf("only square boards are supported, got %dx%d", rec.Width, rec.Height)
+	}
+
+	session := NewSession(SessionOptions{BoardSide: rec.Width, WinLength: winLength, Rules: Freestyle,
+		PlayerX: HumanPlayer("First"), PlayerO: HumanPlayer("Second")})
+
+	for i, move := range rec.Moves {
+		if err := session.place(session.Turn, move.Pos); err != nil {
+			return nil, fmt.Errorf("move %d: %v", i+1, err)
+		}
+	}
+
+	return session, nil
+}
+
+// PSQ converts session history into a Piskvork record, move times are set to zero
+func (s *Session) PSQ() *PSQRecord {
+
+	rec := &PSQRecord{Width: s.Board.CellsHoriz, Height: s.Board.CellsVert,
+		Moves: make([]PSQMove, len(s.History))}
+
+	for i, move := range s.History {
+		rec.Moves[i] = PSQMove{Pos: move.Pos}
+	}
+
+	switch s.Status {
+	case GameWon, GameResigned, GameTimeout:
+		rec.Result = PSQSecondWon
+		if s.Winner == s.firstMove() {
+			rec.Result = PSQFirstWon
+		}
+	case GameDraw:
+		rec.Result = PSQDraw
+	default:
+		rec.Result = PSQUnfinished
+	}
+
+	return rec
+}
@@ -0,0 +1,106 @@
+package misc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func readPSQFile(t *testing.T, fileName string) (*PSQRecord, []byte) {
+
+	data, err := ioutil.ReadFile(fileName)
+	assertNoError(t, err)
+
+	rec, err := ReadPSQ(bytes.NewReader(data))
+	assertNoError(t, err)
+
+	return rec, data
+}
+
+func TestPSQRoundTrip(t *testing.T) {
+
+	for _, fileName := range []string{"testdata/won.psq", "testdata/unfinished.psq"} {
+
+		rec, data := readPSQFile(t, fileName)
+
+		var buf bytes.Buffer
+		assertNoError(t, rec.Write(&buf))
+
+		if buf.String() != string(data) {
+			t.Fatalf("Round trip of %v failed:\n%v", fileName, buf.String())
+		}
+	}
+}
+
+func TestPSQRecord(t *testing.T) {
+
+	rec, _ := readPSQFile(t, "testdata/won.psq")
+
+	assertEqual(t, rec.Width, 20)
+	assertEqual(t, rec.Height, 20)
+	assertEqual(t, rec.Score, [2]int{11, 11})
+	assertEqual(t, rec.Result, PSQFirstWon)
+	assertEqual(t, len(rec.Moves), 9)
+	assertEqual(t, rec.Moves[1], PSQMove{CellPosition{9, 10}, 1250})
+	assertEqual(t, rec.Trailer, []string{"pbrain-goblin.exe", "pbrain-random.exe", "-1"})
+}
+
+func TestPSQSession(t *testing.T) {
+
+	for _, fileName := range []string{"testdata/won.psq", "testdata/unfinished.psq"} {
+
+		rec, _ := readPSQFile(t, fileName)
+
+		session, err := rec.Session(5)
+		assertNoError(t, err)
+
+		exported := session.PSQ()
+
+		assertEqual(t, exported.Width, rec.Width)
+		assertEqual(t, exported.Result, rec.Result)
+		assertEqual(t, len(exported.Moves), len(rec.Moves))
+
+		for i, move := range exported.Moves {
+			assertEqual(t, move.Pos, rec.Moves[i].Pos)
+		}
+	}
+
+	rec, _ := readPSQFile(t, "testdata/won.psq")
+	session, _ := rec.Session(5)
+
+	assertEqual(t, session.Status, GameWon)
+	assertEqual(t, session.Winner, Cell(X))
+	assertEqual(t, session.Board.GetCell(13, 9), Cell(X))
+	assertEqual(t, session.Board.GetCell(8, 9), Cell(O))
+}
+
+func TestPSQErrors(t *testing.T) {
+
+	records := []string{
+		"",
+		"Gomoku 20x20, 0:0, 0\n",
+		"Piskvorky 20-20, 0:0, 0\n",
+		"Piskvorky 20x20, 0:0\n",
+		"Piskvorky 10x10, 0:0, 0\n11,1,0\n",
+		"Piskvorky 1000000000x1000000000, 0:0, 0\n1,1,0\n",
+		"Piskvorky 26x26, 0:0, 0\n",
+	}
+
+	for _, record := range records {
+		if _, err := ReadPSQ(strings.NewReader(record)); err == nil {
+			t.Fatalf("Broken record %q has been accepted", record)
+		}
+	}
+
+	// Windows line endings are fine
+	rec, err := ReadPSQ(strings.NewReader("Piskvorky 10x10, 0:0, 0\r\n1,1,0\r\n2,2,0\r\n"))
+	assertNoError(t, err)
+	assertEqual(t, len(rec.Moves), 2)
+
+	// occupied cell
+	rec, _ = ReadPSQ(strings.NewReader("Piskvorky 10x10, 0:0, 0\n1,1,0\n1,1,0\n"))
+	if _, err := rec.Session(5); err == nil {
+		t.Fatalf("Replay of an illegal move succeeded")
+	}
+}
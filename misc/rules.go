@@ -0,0 +1,71 @@
+package misc
+
+import (
+	"fmt"
+	"math"
+)
+
+// Rules defines which moves are allowed during a game
+type Rules uint8
+
+const (
+	// Freestyle allows any free cell at any time
+	Freestyle Rules = iota
+
+	// Pro requires the first move to be made in the center of the board
+	// and the second move of the first player to be at least proDistance
+	// cells away from the center
+	Pro
+)
+
+const proDistance = 3
+
+func (r Rules) String() string {
+	switch r {
+	case Freestyle:
+		return "freestyle"
+	case Pro:
+		return "pro"
+	}
+	return "unknown"
+}
+
+// ParseRules converts rules name returned by Rules.String back to Rules
+func ParseRules(name string) (Rules, error) {
+	for _, rules := range []Rules{Freestyle, Pro} {
+		if rules.String() == name {
+			return rules, nil
+		}
+	}
+	return Freestyle, fmt.Errorf("unknown rules %q", name)
+}
+
+// boardCenter returns the central cell of a board
+func boardCenter(board *BoardDescription) CellPosition {
+	return CellPosition{board.CellsHoriz / 2, board.CellsVert / 2}
+}
+
+// checkMove verifies that the move number moveIdx (counting from zero) at pos
+// is allowed by the rules, the position is assumed to be inside the board and free
+func (r Rules) checkMove(board *BoardDescription, moveIdx int, pos CellPosition) error {
+
+	if r != Pro {
+		return nil
+	}
+
+	center := boardCenter(board)
+
+	switch moveIdx {
+	case 0:
+		if pos != center {
+			return ErrIllegalMove
+		}
+	case 2:
+		dist := math.Max(math.Abs(float64(pos.Col-center.Col)), math.Abs(float64(pos.Row-center.Row)))
+		if int(dist) < proDistance {
+			return ErrIllegalMove
+		}
+	}
+
+	return nil
+}
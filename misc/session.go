@@ -0,0 +1,296 @@
+package misc
+
+import "time"
+
+// MaxBoardSide is the largest board side accepted by loaders and frontends,
+// GTP vertices end at 25 columns and wider boards need two-letter column names
+const MaxBoardSide = 25
+
+// GameStatus describes whether a game is still going on and how it has ended
+type GameStatus uint8
+
+const (
+	GameInProgress GameStatus = iota
+	GameWon
+	GameDraw
+	// GameResigned means that the loser has resigned, Winner is set to the other side
+	GameResigned
+	// GameTimeout means that the loser has run out of time, Winner is set to the other side
+	GameTimeout
+)
+
+func (s GameStatus) String() string {
+	switch s {
+	case GameInProgress:
+		return "in progress"
+	case GameWon:
+		return "won"
+	case GameDraw:
+		return "draw"
+	case GameResigned:
+		return "resigned"
+	case GameTimeout:
+		return "timeout"
+	}
+	return "unknown"
+}
+
+// MoveError is returned when a move can't be made
+type MoveError string
+
+func (e MoveError) Error() string {
+	return string(e)
+}
+
+const (
+	ErrGameOver    MoveError = "game is over"
+	ErrWrongTurn   MoveError = "it is not this player's turn"
+	ErrOutOfBounds MoveError = "cell is out of the board"
+	ErrOccupied    MoveError = "cell is already occupied"
+	ErrIllegalMove MoveError = "move is not allowed by the rules"
+	ErrNoMoves     MoveError = "there are no moves to take back"
+)
+
+// winPattern returns a sequence of length cells of a given player
+func winPattern(player Cell, length int) []Cell {
+	pattern := make([]Cell, length)
+	for i := range pattern {
+		pattern[i] = player
+	}
+	return pattern
+}
+
+// Player returns settings of a player playing for side
+func (s *Session) Player(side Cell) *Player {
+	if side == O {
+		return &s.PlayerO
+	}
+	return &s.PlayerX
+}
+
+// EngineToMove returns true if the game goes on and it is engine's turn
+func (s *Session) EngineToMove() bool {
+	return !s.Over() && s.Player(s.Turn).Kind == Engine
+}
+
+// Over returns true if the game has already finished
+func (s *Session) Over() bool {
+	return s.Status != GameInProgress
+}
+
+// Play makes a human move at pos for the side to move
+func (s *Session) Play(pos CellPosition) error {
+	if s.Over() {
+		return ErrGameOver
+	}
+	if s.Player(s.Turn).Kind != Human {
+		return ErrWrongTurn
+	}
+	return s.place(s.Turn, pos)
+}
+
+// PlayAs makes a move for player whoever controls the side, it is meant for
+// frontends which relay moves of both sides like text protocols
+func (s *Session) PlayAs(player Cell, pos CellPosition) error {
+	return s.place(player, pos)
+}
+
+// Resign ends the game with a loss of player
+func (s *Session) Resign(player Cell) error {
+
+	if s.Over() {
+		return ErrGameOver
+	}
+
+	s.stopClocks()
+
+	s.Status = GameResigned
+	s.Winner = switchPlayer(player)
+
+	return nil
+}
+
+// Undo takes back the last move, the game is resumed if it was over
+func (s *Session) Undo() error {
+
+	if len(s.History) == 0 {
+		return ErrNoMoves
+	}
+
+	last := s.History[len(s.History)-1]
+
+	s.Board.SetCell(last.Pos.Col, last.Pos.Row, E)
+	s.History = s.History[:len(s.History)-1]
+
+	s.Turn = last.Player
+	s.Status = GameInProgress
+	s.Winner = E
+	s.Intervals = []Interval{}
+
+	// clocks are not turned back, the side to move just gets its clock running
+	if s.TimeControl.Timed() {
+		s.stopClocks()
+		s.Clock(s.Turn).start(timeNow())
+	}
+
+	return nil
+}
+
+// Position returns a new board with the first n moves of the game
+func (s *Session) Position(n int) *BoardDescription {
+
+	if n > len(s.History) {
+		n = len(s.History)
+	}
+
+	board := NewBoard(s.Board.CellsHoriz, s.Board.CellsVert)
+	for _, move := range s.History[:n] {
+		board.SetCell(move.Pos.Col, move.Pos.Row, move.Player)
+	}
+
+	return board
+}
+
+// MakeMove lets engine choose and make a move for the side to move
+func (s *Session) MakeMove() error {
+	return s.MakeMoveProgress(nil)
+}
+
+// MakeMoveProgress is MakeMove which reports engine search progress to a given function
+func (s *Session) MakeMoveProgress(progress ProgressFunc) error {
+
+	pos, err := s.EngineMove(progress, nil)
+	if err != nil {
+		return err
+	}
+
+	return s.place(s.Turn, pos)
+}
+
+// EngineMove chooses a move for the engine playing the side to move without
+// making it, search is cut short when stop is closed and the best move found
+// so far is returned then
+func (s *Session) EngineMove(progress ProgressFunc, stop <-chan struct{}) (CellPosition, error) {
+
+	if s.Over() {
+		return CellPosition{}, ErrGameOver
+	}
+	player := s.Player(s.Turn)
+	if player.Kind != Engine {
+		return CellPosition{}, ErrWrongTurn
+	}
+
+	return s.engineMove(s.searchOptions(player.AI, stop), progress)
+}
+
+// searchOptions completes engine settings for a search made for the side to move
+func (s *Session) searchOptions(options AIOptions, stop <-chan struct{}) AIOptions {
+
+	options.stop = stop
+
+	// engine spends time according to its clock, its own time limit is kept if it is shorter
+	if s.TimeControl.Timed() {
+		t := s.thinkingTime(s.Turn)
+		if t < time.Millisecond {
+			t = time.Millisecond
+		}
+		if options.TimeLimit <= 0 || t < options.TimeLimit {
+			options.TimeLimit = t
+		}
+	}
+
+	return options
+}
+
+// Copy returns a session which shares nothing with s but players' settings,
+// it lets engine search a position while the original session is in use
+func (s *Session) Copy() *Session {
+
+	c := *s
+
+	c.Board = CloneBoard(s.Board)
+	c.History = append([]Move(nil), s.History...)
+	c.Intervals = append([]Interval(nil), s.Intervals...)
+
+	return &c
+}
+
+// engineMove asks engine for a move and falls back to the best rated
+// legal cell if the engine's choice is not allowed by the rules
+func (s *Session) engineMove(options AIOptions, progress ProgressFunc) (CellPosition, error) {
+
+	pos, err := BestMoveProgress(s.Board, options, progress)
+	if err == nil && s.checkMove(options.AIPlayer, pos) == nil {
+		return pos, nil
+	}
+
+	moves := RankMoves(s.Board, options, 100)
+
+	for _, move := range moves {
+		col, row, _ := s.Board.FromLinear(move.Fst)
+		if s.checkMove(options.AIPlayer, CellPosition{col, row}) == nil {
+			return CellPosition{col, row}, nil
+		}
+	}
+
+	for _, idx := range s.Board.GetFreeIndices() {
+		col, row, _ := s.Board.FromLinear(idx)
+		if s.checkMove(options.AIPlayer, CellPosition{col, row}) == nil {
+			return CellPosition{col, row}, nil
+		}
+	}
+
+	return CellPosition{}, ErrIllegalMove
+}
+
+// checkMove verifies whether player is allowed to move at pos
+func (s *Session) checkMove(player Cell, pos CellPosition) error {
+
+	if s.Over() {
+		return ErrGameOver
+	}
+
+	if player != s.Turn {
+		return ErrWrongTurn
+	}
+
+	if _, err := s.Board.ToLinear(pos.Col, pos.Row); err != nil {
+		return ErrOutOfBounds
+	}
+
+	if s.Board.GetCell(pos.Col, pos.Row) != E {
+		return ErrOccupied
+	}
+
+	return s.Rules.checkMove(s.Board, len(s.History), pos)
+}
+
+// place puts player's stone at pos, records the move and updates game status
+func (s *Session) place(player Cell, pos CellPosition) error {
+
+	// a move made after the flag has fallen is too late
+	s.CheckTime()
+
+	if err := s.checkMove(player, pos); err != nil {
+		return err
+	}
+
+	s.Board.SetCell(pos.Col, pos.Row, player)
+	s.History = append(s.History, Move{pos, player})
+
+	intervals := FindPattern(s.Board, winPattern(player, s.WinLength))
+
+	if len(intervals) != 0 {
+		s.Status = GameWon
+		s.Winner = player
+		s.Intervals = intervals
+	} else if s.Board.NumFreeCells() == 0 {
+		s.Status = GameDraw
+	} else {
+		s.Turn = switchPlayer(player)
+	}
+
+	s.switchClocks(player)
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package misc
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSessionPlay(t *testing.T) {
+
+	session := CreateNewSession(6, 4, X)
+
+	assertEqual(t, session.Turn, Cell(X))
+
+	assertEqual(t, session.Play(CellPosition{6, 0}), error(ErrOutOfBounds))
+	assertEqual(t, session.Play(CellPosition{-1, 2}), error(ErrOutOfBounds))
+
+	assertNoError(t, session.Play(CellPosition{2, 2}))
+	assertEqual(t, session.Board.GetCell(2, 2), Cell(X))
+	assertEqual(t, session.Turn, Cell(O))
+
+	// now it is AI's turn
+	assertEqual(t, session.Play(CellPosition{3, 3}), error(ErrWrongTurn))
+	assertEqual(t, session.place(X, CellPosition{3, 3}), error(ErrWrongTurn))
+	assertEqual(t, session.place(O, CellPosition{2, 2}), error(ErrOccupied))
+
+	assertNoError(t, session.place(O, CellPosition{3, 3}))
+	assertEqual(t, len(session.History), 2)
+	assertEqual(t, session.History[1], Move{CellPosition{3, 3}, O})
+
+	// AI can't move out of its turn
+	assertEqual(t, session.MakeMove(), error(ErrWrongTurn))
+}
+
+func TestSessionWin(t *testing.T) {
+
+	session := CreateNewSession(6, 4, X)
+
+	for i := 0; i < 3; i++ {
+		assertNoError(t, session.Play(CellPosition{i, 0}))
+		assertNoError(t, session.place(O, CellPosition{i, 1}))
+	}
+
+	assertNoError(t, session.Play(CellPosition{3, 0}))
+
+	assertEqual(t, session.Status, GameWon)
+	assertEqual(t, session.Winner, Cell(X))
+	assertEqual(t, session.Intervals[0], Interval{horizontal, CellPosition{0, 0}, CellPosition{3, 0}})
+
+	// nothing can be played after the game is over
+	assertEqual(t, session.Play(CellPosition{5, 5}), error(ErrGameOver))
+	assertEqual(t, session.place(O, CellPosition{5, 5}), error(ErrGameOver))
+	assertEqual(t, session.MakeMove(), error(ErrGameOver))
+}
+
+func TestSessionDraw(t *testing.T) {
+
+	session := CreateNewSession(3, 3, X)
+
+	moves := []CellPosition{{0, 0}, {1, 0}, {2, 0}, {1, 1}, {0, 1}, {2, 1}, {1, 2}, {0, 2}, {2, 2}}
+
+	for _, pos := range moves {
+		assertNoError(t, session.place(session.Turn, pos))
+	}
+
+	assertEqual(t, session.Status, GameDraw)
+	assertEqual(t, session.Winner, Cell(E))
+	assertEqual(t, session.place(session.Turn, CellPosition{0, 0}), error(ErrGameOver))
+}
+
+func TestSessionProRules(t *testing.T) {
+
+	session := CreateNewSession(15, 5, X)
+	session.Rules = Pro
+
+	assertEqual(t, session.Play(CellPosition{0, 0}), error(ErrIllegalMove))
+	assertNoError(t, session.Play(CellPosition{7, 7}))
+	assertNoError(t, session.place(O, CellPosition{8, 8}))
+
+	assertEqual(t, session.Play(CellPosition{9, 9}), error(ErrIllegalMove))
+	assertEqual(t, session.Play(CellPosition{5, 9}), error(ErrIllegalMove))
+	assertNoError(t, session.Play(CellPosition{10, 7}))
+}
+
+func TestSessionHumanVsHuman(t *testing.T) {
+
+	session := NewSession(SessionOptions{BoardSide: 6, WinLength: 4,
+		PlayerX: HumanPlayer("Alice"), PlayerO: HumanPlayer("Bob")})
+
+	assertNoError(t, session.Play(CellPosition{0, 0}))
+	assertNoError(t, session.Play(CellPosition{1, 1}))
+
+	assertEqual(t, session.Board.GetCell(1, 1), Cell(O))
+	assertEqual(t, session.EngineToMove(), false)
+	assertEqual(t, session.MakeMove(), error(ErrWrongTurn))
+}
+
+func TestSessionEngineVsEngine(t *testing.T) {
+
+	session := NewSession(SessionOptions{BoardSide: 4, WinLength: 3,
+		PlayerX: EnginePlayer("first", 1), PlayerO: EnginePlayer("second", 2)})
+
+	assertEqual(t, session.PlayerX.AI.AIPlayer, Cell(X))
+	assertEqual(t, session.PlayerO.AI.AIPlayer, Cell(O))
+	assertEqual(t, session.PlayerO.AI.MaxDepth(), 2)
+
+	assertEqual(t, session.Play(CellPosition{0, 0}), error(ErrWrongTurn))
+
+	for moves := 1; session.EngineToMove(); moves++ {
+		assertNoError(t, session.MakeMove())
+		assertEqual(t, len(session.History), moves)
+	}
+
+	assertEqual(t, session.Over(), true)
+}
+
+func TestSessionEngineMovesFirst(t *testing.T) {
+
+	session := NewSession(SessionOptions{BoardSide: 9, WinLength: 5, FirstMove: O,
+		PlayerX: HumanPlayer("Human"), PlayerO: EnginePlayer("Goblin", 1)})
+
+	assertEqual(t, session.Turn, Cell(O))
+	assertEqual(t, session.EngineToMove(), true)
+
+	// engine opens in the center
+	assertNoError(t, session.MakeMove())
+	assertEqual(t, session.History[0], Move{CellPosition{4, 4}, O})
+
+	assertNoError(t, session.Play(CellPosition{0, 0}))
+	assertEqual(t, session.Turn, Cell(O))
+}
+
+func TestSessionUndo(t *testing.T) {
+
+	session := NewSession(SessionOptions{BoardSide: 5, WinLength: 3,
+		PlayerX: HumanPlayer("Alice"), PlayerO: EnginePlayer("Goblin", 1)})
+
+	assertEqual(t, session.Undo(), error(ErrNoMoves))
+
+	assertNoError(t, session.PlayAs(X, CellPosition{0, 0}))
+	assertNoError(t, session.PlayAs(O, CellPosition{4, 4}))
+	assertNoError(t, session.PlayAs(X, CellPosition{1, 0}))
+	assertNoError(t, session.PlayAs(O, CellPosition{4, 3}))
+	assertNoError(t, session.PlayAs(X, CellPosition{2, 0}))
+
+	assertEqual(t, session.Status, GameWon)
+
+	assertNoError(t, session.Undo())
+	assertEqual(t, session.Status, GameInProgress)
+	assertEqual(t, session.Winner, Cell(E))
+	assertEqual(t, session.Turn, Cell(X))
+	assertEqual(t, session.Board.GetCell(2, 0), Cell(E))
+	assertEqual(t, len(session.History), 4)
+
+	assertNoError(t, session.Undo())
+	assertEqual(t, session.Turn, Cell(O))
+	assertEqual(t, session.Board.GetCell(4, 3), Cell(E))
+}
+
+func TestSessionPosition(t *testing.T) {
+
+	session := CreateNewSession(6, 4, X)
+
+	assertNoError(t, session.Play(CellPosition{2, 2}))
+	assertNoError(t, session.place(O, CellPosition{3, 3}))
+
+	board := session.Position(1)
+	assertEqual(t, board.GetCell(2, 2), Cell(X))
+	assertEqual(t, board.GetCell(3, 3), Cell(E))
+
+	assertEqual(t, session.Position(0).NumFreeCells(), 36)
+	assertEqual(t, session.Position(5).GetCell(3, 3), Cell(O))
+
+	// position is a copy
+	session.Position(2).SetCell(0, 0, X)
+	assertEqual(t, session.Board.GetCell(0, 0), Cell(E))
+}
+
+func TestSessionEngineMoveStopped(t *testing.T) {
+
+	session := CreateNewSession(9, 5, X)
+	assertNoError(t, session.place(X, CellPosition{4, 4}))
+
+	// engine moves without waiting for a deep search once it is stopped
+	stop := make(chan struct{})
+	close(stop)
+
+	copied := session.Copy()
+
+	pos, err := copied.EngineMove(nil, stop)
+	assertNoError(t, err)
+	assertNoError(t, copied.checkMove(O, pos))
+
+	// the move is not made and the copy shares nothing with the session
+	assertEqual(t, len(copied.History), 1)
+	copied.place(O, pos)
+	assertEqual(t, len(session.History), 1)
+	assertEqual(t, session.Board.GetCell(pos.Col, pos.Row), Cell(E))
+
+	assertNoError(t, session.place(O, pos))
+	_, err = session.EngineMove(nil, nil)
+	assertEqual(t, err, error(ErrWrongTurn))
+}
+
+func TestSessionEngineUrgentMoves(t *testing.T) {
+
+	// X has an open four, O has three in a column
+	moves := []CellPosition{{3, 7}, {10, 10}, {4, 7}, {10, 11}, {5, 7}, {10, 12}, {6, 7}}
+
+	stop := make(chan struct{})
+	close(stop)
+
+	for _, limit := range []time.Duration{0, time.Nanosecond} {
+
+		session := CreateNewSession(15, 5, X)
+		session.PlayerO.AI.TimeLimit = limit
+
+		for i, pos := range moves {
+			assertNoError(t, session.place([]Cell{X, O}[i%2], pos))
+		}
+
+		// a stopped or unfinished search still blocks the four
+		pos, err := session.EngineMove(nil, stop)
+		assertNoError(t, err)
+		if pos != (CellPosition{2, 7}) && pos != (CellPosition{7, 7}) {
+			t.Fatalf("time limit %v: engine hasn't blocked the four, played %v", limit, pos)
+		}
+
+		// and takes a win in one first
+		assertNoError(t, session.place(O, pos))
+		assertNoError(t, session.place(X, CellPosition{0, 0}))
+		assertNoError(t, session.place(O, CellPosition{10, 13}))
+		assertNoError(t, session.place(X, CellPosition{0, 2}))
+
+		pos, err = session.EngineMove(nil, stop)
+		assertNoError(t, err)
+		if pos != (CellPosition{10, 9}) && pos != (CellPosition{10, 14}) {
+			t.Fatalf("time limit %v: engine has missed the win, played %v", limit, pos)
+		}
+	}
+}
+
+func TestSessionResign(t *testing.T) {
+
+	session := CreateNewSession(5, 3, X)
+
+	assertNoError(t, session.Play(CellPosition{2, 2}))
+	assertNoError(t, session.Resign(X))
+
+	assertEqual(t, session.Status, GameResigned)
+	assertEqual(t, session.Winner, Cell(O))
+	assertEqual(t, session.Resign(O), error(ErrGameOver))
+	assertEqual(t, session.MakeMove(), error(ErrGameOver))
+
+	var buf bytes.Buffer
+	assertNoError(t, session.Save(&buf))
+
+	loaded, err := LoadSession(&buf)
+	assertNoError(t, err)
+	assertEqual(t, loaded.Status, GameResigned)
+	assertEqual(t, loaded.Winner, Cell(O))
+
+	result, _ := session.SGF().Root.Get("RE")
+	assertEqual(t, result, "W+R")
+}
@@ -0,0 +1,445 @@
+package misc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// SGF game records support
+//
+// Five-in-a-row dialect of SGF uses GM[4], board size is set by SZ, black
+// moves (B) are made by X and white moves (W) by O. Coordinates are pairs of
+// letters, column first, counting from the upper-left corner: "a" to "z"
+// stand for 0..25 and "A" to "Z" for 26..51.
+
+const sgfGameType = "4"
+
+// SGFProperty is a property of SGF node with all of its values
+type SGFProperty struct {
+	Ident  string
+	Values []string
+}
+
+// SGFNode is a node of SGF game tree, the first child continues main line
+// and all the others start variations
+type SGFNode struct {
+	Props    []SGFProperty
+	Children []*SGFNode
+}
+
+// SGFGame is a game tree read from SGF file
+type SGFGame struct {
+	Root *SGFNode
+}
+
+// SGFError describes a syntax error in SGF data
+type SGFError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *SGFError) Error() string {
+	return fmt.Sprintf("sgf: offset %d: %s", e.Offset, e.Msg)
+}
+
+// Get returns the first value of property ident
+func (n *SGFNode) Get(ident string) (string, bool) {
+	for _, prop := range n.Props {
+		if prop.Ident == ident && len(prop.Values) != 0 {
+			return prop.Values[0], true
+		}
+	}
+	return "", false
+}
+
+// Set replaces values of property ident or adds a new property
+func (n *SGFNode) Set(ident string, values ...string) {
+	for i, prop := range n.Props {
+		if prop.Ident == ident {
+			n.Props[i].Values = values
+			return
+		}
+	}
+	n.Props = append(n.Props, SGFProperty{ident, values})
+}
+
+// Comment returns node comment
+func (n *SGFNode) Comment() string {
+	comment, _ := n.Get("C")
+	return comment
+}
+
+// Move returns a move stored in a node, ok is false if the node has no move
+func (n *SGFNode) Move() (move Move, ok bool, err error) {
+	for _, color := range []string{"B", "W"} {
+		if value, found := n.Get(color); found {
+			pos, err := parseSGFPoint(value)
+			if err != nil {
+				return Move{}, false, err
+			}
+			player := Cell(X)
+			if color == "W" {
+				player = O
+			}
+			return Move{pos, player}, true, nil
+		}
+	}
+	return Move{}, false, nil
+}
+
+// sgfParser is a recursive descent parser of SGF collections
+type sgfParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *sgfParser) errorf(format string, args ...interface{}) error {
+	return &SGFError{p.pos, fmt.Sprintf(format, args...)}
+}
+
+func (p *sgfParser) skipSpace() {
+	for p.pos < len(p.data) && strings.IndexByte(" \t\r\n", p.data[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space character or 0 at the end of data
+func (p *sgfParser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.data) {
+		return p.data[p.pos]
+	}
+	return 0
+}
+
+func (p *sgfParser) expect(c byte) error {
+	if p.peek() != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+// parseTree parses "(" sequence tree* ")" and returns the first node of a sequence
+func (p *sgfParser) parseTree() (*SGFNode, error) {
+
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+
+	var first, last *SGFNode
+
+	for p.peek() == ';' {
+		p.pos++
+		node, err := p.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = node
+		} else {
+			last.Children = append(last.Children, node)
+		}
+		last = node
+	}
+
+	if first == nil {
+		return nil, p.errorf("empty game tree")
+	}
+
+	for p.peek() == '(' {
+		child, err := p.parseTree()
+		if err != nil {
+			return nil, err
+		}
+		last.Children = append(last.Children, child)
+	}
+
+	return first, p.expect(')')
+}
+
+func (p *sgfParser) parseNode() (*SGFNode, error) {
+
+	node := &SGFNode{}
+
+	for {
+		c := p.peek()
+		if c < 'A' || c > 'Z' {
+			return node, nil
+		}
+
+		start := p.pos
+		for p.pos < len(p.data) && p.data[p.pos] >= 'A' && p.data[p.pos] <= 'Z' {
+			p.pos++
+		}
+		prop := SGFProperty{Ident: string(p.data[start:p.pos])}
+
+		for p.peek() == '[' {
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			prop.Values = append(prop.Values, value)
+		}
+
+		if len(prop.Values) == 0 {
+			return nil, p.errorf("property %s has no values", prop.Ident)
+		}
+
+		node.Props = append(node.Props, prop)
+	}
+}
+
+func (p *sgfParser) parseValue() (string, error) {
+
+	p.pos++ // skip '['
+
+	var value []byte
+
+	for ; p.pos < len(p.data); p.pos++ {
+		switch c := p.data[p.pos]; c {
+		case ']':
+			p.pos++
+			return string(value), nil
+		case '\\':
+			p.pos++
+			// escaped line break is a soft break and is removed
+			if p.pos < len(p.data) && p.data[p.pos] != '\n' {
+				value = append(value, p.data[p.pos])
+			}
+		default:
+			value = append(value, c)
+		}
+	}
+
+	return "", p.errorf("unterminated property value")
+}
+
+// ParseSGF reads all game trees of SGF collection
+func ParseSGF(r io.Reader) ([]*SGFGame, error) {
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &sgfParser{data: data}
+	games := []*SGFGame{}
+
+	for p.peek() == '(' {
+		root, err := p.parseTree()
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, &SGFGame{root})
+	}
+
+	if p.pos < len(p.data) {
+		return nil, p.errorf("unexpected %q", p.data[p.pos])
+	}
+
+	if len(games) == 0 {
+		return nil, p.errorf("no game trees found")
+	}
+
+	return games, nil
+}
+
+func escapeSGFValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `]`, `\]`).Replace(value)
+}
+
+func writeSGFTree(w *bufio.Writer, node *SGFNode) {
+
+	w.WriteByte('(')
+
+	for {
+		w.WriteByte(';')
+		for _, prop := range node.Props {
+			w.WriteString(prop.Ident)
+			for _, value := range prop.Values {
+				w.WriteString("[" + escapeSGFValue(value) + "]")
+			}
+		}
+
+		if len(node.Children) != 1 {
+			break
+		}
+		node = node.Children[0]
+	}
+
+	for _, child := range node.Children {
+		w.WriteByte('\n')
+		writeSGFTree(w, child)
+	}
+
+	w.WriteByte(')')
+}
+
+// WriteSGF writes games as SGF collection
+func WriteSGF(w io.Writer, games ...*SGFGame) error {
+	bw := bufio.NewWriter(w)
+	for _, game := range games {
+		writeSGFTree(bw, game.Root)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+func sgfCoord(c byte) (int, bool) {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 26, true
+	}
+	return 0, false
+}
+
+func parseSGFPoint(value string) (CellPosition, error) {
+	if len(value) == 2 {
+		col, okCol := sgfCoord(value[0])
+		row, okRow := sgfCoord(value[1])
+		if okCol && okRow {
+			return CellPosition{col, row}, nil
+		}
+	}
+	return CellPosition{}, fmt.Errorf("invalid point %q", value)
+}
+
+func formatSGFCoord(c int) byte {
+	if c < 26 {
+		return byte('a' + c)
+	}
+	return byte('A' + c - 26)
+}
+
+func formatSGFPoint(pos CellPosition) string {
+	return string([]byte{formatSGFCoord(pos.Col), formatSGFCoord(pos.Row)})
+}
+
+// MainLine returns nodes of the main line starting from the root
+func (g *SGFGame) MainLine() []*SGFNode {
+	nodes := []*SGFNode{}
+	for node := g.Root; node != nil; {
+		nodes = append(nodes, node)
+		if len(node.Children) == 0 {
+			break
+		}
+		node = node.Children[0]
+	}
+	return nodes
+}
+
+// BoardSide returns board size set by SZ property, 15 is the default for five-in-a-row
+func (g *SGFGame) BoardSide() (int, error) {
+
+	size, found := g.Root.Get("SZ")
+	if !found {
+		return 15, nil
+	}
+
+	// rectangular boards are written as "cols:rows"
+	sides := strings.Split(size, ":")
+	if len(sides) == 2 && sides[0] != sides[1] {
+		return 0, fmt.Errorf("only square boards are supported, got %s", size)
+	}
+
+	side, err := strconv.Atoi(sides[0])
+	if err != nil || side <= 0 || side > MaxBoardSide {
+		return 0, fmt.Errorf("invalid board size %q", size)
+	}
+
+	return side, nil
+}
+
+// Session replays main line of the game in a new human vs human session
+func (g *SGFGame) Session(winLength int) (*Session, error) {
+
+	if gameType, found := g.Root.Get("GM"); found && gameType != sgfGameType {
+		return nil, fmt.Errorf("not a five-in-a-row game, GM[%s]", gameType)
+	}
+
+	side, err := g.BoardSide()
+	if err != nil {
+		return nil, err
+	}
+
+	moves := []Move{}
+	for _, node := range g.MainLine() {
+		move, ok, err := node.Move()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			moves = append(moves, move)
+		}
+	}
+
+	options := SessionOptions{BoardSide: side, WinLength: winLength, Rules: Freestyle,
+		PlayerX: HumanPlayer("Black"), PlayerO: HumanPlayer("White")}
+
+	if name, found := g.Root.Get("PB"); found {
+		options.PlayerX.Name = name
+	}
+	if name, found := g.Root.Get("PW"); found {
+		options.PlayerO.Name = name
+	}
+	if len(moves) != 0 {
+		options.FirstMove = moves[0].Player
+	}
+
+	session := NewSession(options)
+
+	for i, move := range moves {
+		if err := session.place(move.Player, move.Pos); err != nil {
+			return nil, fmt.Errorf("move %d: %v", i+1, err)
+		}
+	}
+
+	return session, nil
+}
+
+// SGF converts session history into SGF game without variations
+func (s *Session) SGF() *SGFGame {
+
+	root := &SGFNode{}
+	root.Set("FF", "4")
+	root.Set("GM", sgfGameType)
+	root.Set("SZ", strconv.Itoa(s.Board.CellsHoriz))
+	root.Set("PB", s.PlayerX.Name)
+	root.Set("PW", s.PlayerO.Name)
+
+	switch s.Status {
+	case GameWon, GameResigned, GameTimeout:
+		result := "W+"
+		if s.Winner == X {
+			result = "B+"
+		}
+		if s.Status == GameResigned {
+			result += "R"
+		} else if s.Status == GameTimeout {
+			result += "T"
+		}
+		root.Set("RE", result)
+	case GameDraw:
+		root.Set("RE", "0")
+	}
+
+	node := root
+	for _, move := range s.History {
+		color := "B"
+		if move.Player == O {
+			color = "W"
+		}
+		child := &SGFNode{Props: []SGFProperty{{color, []string{formatSGFPoint(move.Pos)}}}}
+		node.Children = append(node.Children, child)
+		node = child
+	}
+
+	return &SGFGame{root}
+}
@@ -0,0 +1,126 @@
+package misc
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func readSGFFile(t *testing.T, fileName string) *SGFGame {
+
+	f, err := os.Open(fileName)
+	assertNoError(t, err)
+	defer f.Close()
+
+	games, err := ParseSGF(f)
+	assertNoError(t, err)
+	assertEqual(t, len(games), 1)
+
+	return games[0]
+}
+
+func TestSGFParse(t *testing.T) {
+
+	game := readSGFFile(t, "testdata/variations.sgf")
+
+	assertEqual(t, game.Root.Comment(), "Friendly game [blitz]")
+
+	name, _ := game.Root.Get("PB")
+	assertEqual(t, name, "Alice")
+
+	mainLine := game.MainLine()
+	assertEqual(t, len(mainLine), 12)
+	assertEqual(t, mainLine[1].Comment(), "Center opening")
+
+	// variations start after the fourth move
+	assertEqual(t, len(mainLine[4].Children), 3)
+	assertEqual(t, mainLine[4].Children[1].Comment(), "Also good")
+
+	move, ok, err := mainLine[4].Children[2].Move()
+	assertNoError(t, err)
+	assertEqual(t, ok, true)
+	assertEqual(t, move, Move{CellPosition{9, 9}, X})
+
+	_, ok, _ = game.Root.Move()
+	assertEqual(t, ok, false)
+}
+
+func TestSGFSession(t *testing.T) {
+
+	game := readSGFFile(t, "testdata/variations.sgf")
+
+	session, err := game.Session(5)
+	assertNoError(t, err)
+
+	assertEqual(t, session.PlayerX.Name, "Alice")
+	assertEqual(t, session.PlayerO.Name, "Bob")
+	assertEqual(t, len(session.History), 11)
+	assertEqual(t, session.History[0], Move{CellPosition{7, 7}, X})
+	assertEqual(t, session.Status, GameWon)
+	assertEqual(t, session.Winner, Cell(X))
+
+	exported := session.SGF()
+	result, _ := exported.Root.Get("RE")
+	assertEqual(t, result, "B+")
+
+	mainLine := exported.MainLine()
+	for i, node := range game.MainLine()[1:] {
+		original, _, _ := node.Move()
+		move, _, _ := mainLine[i+1].Move()
+		assertEqual(t, move, original)
+	}
+}
+
+func TestSGFRoundTrip(t *testing.T) {
+
+	game := readSGFFile(t, "testdata/variations.sgf")
+
+	var first bytes.Buffer
+	assertNoError(t, WriteSGF(&first, game))
+
+	games, err := ParseSGF(bytes.NewReader(first.Bytes()))
+	assertNoError(t, err)
+
+	var second bytes.Buffer
+	assertNoError(t, WriteSGF(&second, games...))
+
+	assertEqual(t, first.String(), second.String())
+	assertEqual(t, games[0].Root.Comment(), game.Root.Comment())
+	assertEqual(t, len(games[0].MainLine()[4].Children), 3)
+}
+
+func TestSGFErrors(t *testing.T) {
+
+	records := []string{
+		"",
+		"(;GM[4]",
+		"(;GM[4]C[unterminated)",
+		"(;GM[4];B[hh]) garbage",
+		"()",
+		"(;GM[4]SZ)",
+	}
+
+	for _, record := range records {
+		if _, err := ParseSGF(strings.NewReader(record)); err == nil {
+			t.Fatalf("Broken record %q has been accepted", record)
+		}
+	}
+
+	sessions := []string{
+		"(;GM[1]SZ[19];B[aa])",
+		"(;GM[4]SZ[26];B[aa])",
+		"(;GM[4]SZ[15:13];B[aa])",
+		"(;GM[4]SZ[5];B[aa];W[aa])",
+		"(;GM[4]SZ[5];B[zz])",
+		"(;GM[4]SZ[5];B[a])",
+	}
+
+	for _, record := range sessions {
+		games, err := ParseSGF(strings.NewReader(record))
+		assertNoError(t, err)
+		if _, err := games[0].Session(5); err == nil {
+			t.Fatalf("Broken game %q has been accepted", record)
+		}
+	}
+}
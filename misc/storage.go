@@ -0,0 +1,282 @@
+package misc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// SaveFormatVersion is the version of saved games format written by Save
+const SaveFormatVersion = 2
+
+type savedPlayer struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Depth int    `json:"depth,omitempty"`
+}
+
+type savedMove struct {
+	// version 1 stores cells as numbers
+	Col int `json:"col,omitempty"`
+	Row int `json:"row,omitempty"`
+
+	// since version 2 cells are stored in standard notation
+	Pos string `json:"pos,omitempty"`
+
+	Player string `json:"player"`
+}
+
+type savedResult struct {
+	Status string `json:"status"`
+	Winner string `json:"winner,omitempty"`
+}
+
+type savedGame struct {
+	Version   int         `json:"version"`
+	SessionID string      `json:"session_id"`
+	BoardSide int         `json:"board_side"`
+	WinLength int         `json:"win_length"`
+	Rules     string      `json:"rules"`
+	FirstMove string      `json:"first_move"`
+	PlayerX   savedPlayer `json:"player_x"`
+	PlayerO   savedPlayer `json:"player_o"`
+	Moves     []savedMove `json:"moves"`
+	Result    savedResult `json:"result"`
+
+	// time control and clocks of timed games
+	TimeControl string `json:"time_control,omitempty"`
+	TimeLeftX   string `json:"time_left_x,omitempty"`
+	TimeLeftO   string `json:"time_left_o,omitempty"`
+}
+
+// cellName converts X and O into their string names, empty cell becomes an empty string
+func cellName(c Cell) string {
+	if c == E {
+		return ""
+	}
+	return string(c)
+}
+
+// parseCellName is the inverse of cellName for X and O
+func parseCellName(name string) (Cell, error) {
+	switch name {
+	case "X":
+		return X, nil
+	case "O":
+		return O, nil
+	}
+	return E, fmt.Errorf("unknown player %q", name)
+}
+
+func savePlayer(p Player) savedPlayer {
+	saved := savedPlayer{Kind: p.Kind.String(), Name: p.Name}
+	if p.Kind == Engine {
+		saved.Depth = p.AI.maxDepth
+	}
+	return saved
+}
+
+func loadPlayer(saved savedPlayer) (Player, error) {
+	switch saved.Kind {
+	case Human.String():
+		return HumanPlayer(saved.Name), nil
+	case Engine.String():
+		return EnginePlayer(saved.Name, saved.Depth), nil
+	}
+	return Player{}, fmt.Errorf("unknown player kind %q", saved.Kind)
+}
+
+// firstMove returns the side which has started the game
+func (s *Session) firstMove() Cell {
+	if len(s.History) != 0 {
+		return s.History[0].Player
+	}
+	return s.Turn
+}
+
+// Save writes the whole session as a JSON document
+func (s *Session) Save(w io.Writer) error {
+
+	game := savedGame{
+		Version:   SaveFormatVersion,
+		SessionID: s.SessionID,
+		BoardSide: s.Board.CellsHoriz,
+		WinLength: s.WinLength,
+		Rules:     s.Rules.String(),
+		FirstMove: cellName(s.firstMove()),
+		PlayerX:   savePlayer(s.PlayerX),
+		PlayerO:   savePlayer(s.PlayerO),
+		Moves:     make([]savedMove, len(s.History)),
+		Result:    savedResult{s.Status.String(), cellName(s.Winner)},
+	}
+
+	if s.TimeControl.Timed() {
+		game.TimeControl = s.TimeControl.String()
+		game.TimeLeftX = s.TimeLeft(X).String()
+		game.TimeLeftO = s.TimeLeft(O).String()
+	}
+
+	notation := DefaultNotation(s.Board.CellsVert)
+
+	for i, move := range s.History {
+		game.Moves[i] = savedMove{Pos: notation.Format(move.Pos), Player: cellName(move.Player)}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(game)
+}
+
+// LoadSession reads a session written by Save, validates it and replays all the moves
+func LoadSession(r io.Reader) (*Session, error) {
+
+	var game savedGame
+
+	if err := json.NewDecoder(r).Decode(&game); err != nil {
+		return nil, fmt.Errorf("malformed saved game: %v", err)
+	}
+
+	if game.Version < 1 || game.Version > SaveFormatVersion {
+		return nil, fmt.Errorf("unsupported saved game version %d", game.Version)
+	}
+
+	if game.BoardSide <= 0 || game.BoardSide > MaxBoardSide {
+		return nil, fmt.Errorf("invalid board side %d", game.BoardSide)
+	}
+
+	if game.WinLength <= 1 || game.WinLength > game.BoardSide {
+		return nil, fmt.Errorf("invalid win length %d", game.WinLength)
+	}
+
+	options := SessionOptions{BoardSide: game.BoardSide, WinLength: game.WinLength}
+
+	var err error
+
+	if options.Rules, err = ParseRules(game.Rules); err != nil {
+		return nil, err
+	}
+
+	if options.FirstMove, err = parseCellName(game.FirstMove); err != nil {
+		return nil, err
+	}
+
+	if options.PlayerX, err = loadPlayer(game.PlayerX); err != nil {
+		return nil, err
+	}
+
+	if options.PlayerO, err = loadPlayer(game.PlayerO); err != nil {
+		return nil, err
+	}
+
+	if options.TimeControl, err = ParseTimeControl(game.TimeControl); err != nil {
+		return nil, err
+	}
+
+	session := NewSession(options)
+	session.SessionID = game.SessionID
+
+	notation := DefaultNotation(game.BoardSide)
+
+	for i, move := range game.Moves {
+
+		player, err := parseCellName(move.Player)
+		if err != nil {
+			return nil, fmt.Errorf("move %d: %v", i+1, err)
+		}
+
+		pos := CellPosition{move.Col, move.Row}
+		if game.Version >= 2 {
+			if pos, err = notation.Parse(move.Pos); err != nil {
+				return nil, fmt.Errorf("move %d: %v", i+1, err)
+			}
+		}
+
+		if err := session.place(player, pos); err != nil {
+			return nil, fmt.Errorf("move %d: %v", i+1, err)
+		}
+	}
+
+	if game.Result.Status == GameResigned.String() {
+		winner, err := parseCellName(game.Result.Winner)
+		if err != nil {
+			return nil, err
+		}
+		if err := session.Resign(switchPlayer(winner)); err != nil {
+			return nil, err
+		}
+	}
+
+	if session.TimeControl.Timed() {
+		if err := loadClocks(session, game); err != nil {
+			return nil, err
+		}
+	}
+
+	if session.Status.String() != game.Result.Status || cellName(session.Winner) != game.Result.Winner {
+		return nil, fmt.Errorf("saved result %q doesn't match the moves", game.Result.Status)
+	}
+
+	return session, nil
+}
+
+// loadClocks restores clocks of a timed game, the clock of the side to move
+// is started again and a game lost on time is finished the same way
+func loadClocks(s *Session, game savedGame) error {
+
+	s.stopClocks()
+
+	for _, clock := range []struct {
+		value string
+		clock *Clock
+	}{{game.TimeLeftX, &s.ClockX}, {game.TimeLeftO, &s.ClockO}} {
+		left, err := time.ParseDuration(clock.value)
+		if err != nil || left < 0 {
+			return fmt.Errorf("invalid time left %q", clock.value)
+		}
+		clock.clock.Left = left
+	}
+
+	if s.Over() {
+		return nil
+	}
+
+	s.Clock(s.Turn).start(timeNow())
+
+	if game.Result.Status == GameTimeout.String() && !s.CheckTime() {
+		return fmt.Errorf("saved result %q doesn't match the clocks", game.Result.Status)
+	}
+
+	return nil
+}
+
+// SaveToFile saves session into a file with a given name
+func (s *Session) SaveToFile(fileName string) error {
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// LoadFromFile loads session saved by SaveToFile
+func LoadFromFile(fileName string) (*Session, error) {
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return LoadSession(f)
+}
@@ -0,0 +1,87 @@
+package misc
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoadSession(t *testing.T) {
+
+	session := NewSession(SessionOptions{BoardSide: 7, WinLength: 4, Rules: Pro, FirstMove: O,
+		PlayerX: HumanPlayer("Alice"), PlayerO: EnginePlayer("Goblin", 3)})
+
+	// let the test make moves for both sides
+	session.PlayerO.Kind = Human
+
+	for _, pos := range []CellPosition{{3, 3}, {2, 3}, {0, 0}, {2, 4}, {0, 1}, {2, 5}, {0, 2}, {2, 2}} {
+		assertNoError(t, session.Play(pos))
+	}
+	session.PlayerO.Kind = Engine
+
+	assertEqual(t, session.Status, GameWon)
+	assertEqual(t, session.Winner, Cell(X))
+
+	var buf bytes.Buffer
+	assertNoError(t, session.Save(&buf))
+
+	loaded, err := LoadSession(&buf)
+	assertNoError(t, err)
+
+	assertEqual(t, loaded.SessionID, session.SessionID)
+	assertEqual(t, loaded.Rules, Pro)
+	assertEqual(t, loaded.WinLength, 4)
+	assertEqual(t, loaded.Status, GameWon)
+	assertEqual(t, loaded.Winner, Cell(X))
+	assertEqual(t, loaded.PlayerX, session.PlayerX)
+	assertEqual(t, loaded.PlayerO, session.PlayerO)
+
+	if !reflect.DeepEqual(loaded.History, session.History) {
+		t.Fatalf("History mismatch %v != %v", loaded.History, session.History)
+	}
+	assertEqual(t, loaded.Board.String(), session.Board.String())
+}
+
+func TestLoadSessionErrors(t *testing.T) {
+
+	header := `"session_id": "abc", "board_side": 5, "win_length": 3, "rules": "freestyle",
+		"first_move": "X", "player_x": {"kind": "human"}, "player_o": {"kind": "engine", "depth": 2}`
+
+	games := map[string]string{
+		"version": `{"version": 100, ` + header + `, "result": {"status": "in progress"}}`,
+		"occupied": `{"version": 1, ` + header + `, "moves": [{"col": 1, "row": 1, "player": "X"},
+			{"col": 1, "row": 1, "player": "O"}], "result": {"status": "in progress"}}`,
+		"turn": `{"version": 1, ` + header + `, "moves": [{"col": 1, "row": 1, "player": "O"}],
+			"result": {"status": "in progress"}}`,
+		"result":    `{"version": 1, ` + header + `, "result": {"status": "won", "winner": "X"}}`,
+		"malformed": `{"version": 1, ` + header,
+		"huge board": `{"version": 2, "board_side": 1000000000, "win_length": 5, "rules": "freestyle",
+			"first_move": "X", "player_x": {"kind": "human"}, "player_o": {"kind": "human"}, "result": {"status": "in progress"}}`,
+		"wide board": `{"version": 2, "board_side": 26, "win_length": 5, "rules": "freestyle",
+			"first_move": "X", "player_x": {"kind": "human"}, "player_o": {"kind": "human"}, "result": {"status": "in progress"}}`,
+	}
+
+	for name, game := range games {
+		if _, err := LoadSession(strings.NewReader(game)); err == nil {
+			t.Fatalf("Loading of a broken game %q succeeded", name)
+		}
+	}
+
+	// version 1 stores cells as numbers
+	session, err := LoadSession(strings.NewReader(`{"version": 1, ` + header + `, "moves": [{"col": 1, "row": 2, "player": "X"}],
+		"result": {"status": "in progress"}}`))
+	assertNoError(t, err)
+	assertEqual(t, session.History[0].Pos, CellPosition{1, 2})
+
+	session, err = LoadSession(strings.NewReader(`{"version": 2, ` + header + `, "moves": [{"pos": "B3", "player": "X"}],
+		"result": {"status": "in progress"}}`))
+	assertNoError(t, err)
+	assertEqual(t, session.History[0].Pos, CellPosition{1, 2})
+
+	_, err = LoadSession(strings.NewReader(`{"version": 2, ` + header + `, "moves": [{"pos": "3B", "player": "X"}],
+		"result": {"status": "in progress"}}`))
+	if err == nil {
+		t.Fatalf("Loading of a broken move succeeded")
+	}
+}
@@ -1,5 +1,7 @@
 package misc
 
+import "time"
+
 
 // Mimic python set
 type Set map[interface{}]bool
@@ -37,6 +39,12 @@ type CellPosition struct {
 	Col, Row int
 }
 
+// Move is a single move of a game, as stored in session history
+type Move struct {
+	Pos    CellPosition
+	Player Cell
+}
+
 // Interval represents indexes of start and end of an n-length chain
 
 type Interval struct {
@@ -116,6 +124,49 @@ type AIOptions struct {
 	maxDepth int
 	useGoRoutines bool
 	useAlphaBeta bool
+
+	// TimeLimit bounds time spent on a move, search is not limited if it is zero
+	TimeLimit time.Duration
+
+	// point in time when search must stop, set by BestMove
+	deadline time.Time
+
+	// search stops when stop is closed, set by Session.EngineMove
+	stop <-chan struct{}
+}
+
+// SearchInfo describes current state of a search, depth is zero for
+// the Monte-Carlo estimate made before the tree search
+type SearchInfo struct {
+	Depth   int
+	Best    CellPosition
+	Score   int
+	Elapsed time.Duration
+}
+
+// ProgressFunc receives search state each time search finds a better estimate
+type ProgressFunc func(info SearchInfo)
+
+// report passes search state to the progress callback if there is one
+func (progress ProgressFunc) report(board *BoardDescription, depth, bestLinear, score int, start time.Time) {
+	if progress == nil {
+		return
+	}
+	col, row, err := board.FromLinear(bestLinear)
+	if err != nil {
+		return
+	}
+	progress(SearchInfo{depth, CellPosition{col, row}, score, time.Since(start)})
+}
+
+// timeIsUp returns true if search is stopped or its deadline is set and has passed
+func (o AIOptions) timeIsUp() bool {
+	select {
+	case <-o.stop:
+		return true
+	default:
+	}
+	return !o.deadline.IsZero() && time.Now().After(o.deadline)
 }
 
 const (
@@ -0,0 +1,576 @@
+package netplay
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/risboo6909/goblin/misc"
+)
+
+// Game is a network game seen by one of the peers
+//
+// Session is changed only by Game methods, which must be called from a single
+// goroutine, usually the one running UI. Messages from the peer are delivered
+// by Messages channel and take effect when they are passed to Handle.
+type Game struct {
+	Session *misc.Session
+	Local   misc.Cell
+	ID      string
+
+	LocalName, PeerName string
+
+	// Messages delivers peer messages and local "connected" and "disconnected"
+	// notifications
+	Messages chan Message
+
+	// the peer has asked to undo and waits for an answer
+	UndoRequested bool
+
+	offer   RulesOffer
+	host    bool
+	pending bool // local undo request is waiting for an answer
+
+	mu     sync.Mutex
+	conn   *Conn
+	closed bool
+
+	listener net.Listener // host only
+	addr     string       // guest only
+}
+
+// newGame sets up a game after handshake
+func newGame(offer RulesOffer, host bool, hostName, guestName string) (*Game, error) {
+
+	options, hostSide, err := offer.Options(hostName, guestName)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Game{Session: misc.NewSession(options), offer: offer, host: host,
+		Messages: make(chan Message, 64)}
+
+	g.LocalName, g.PeerName, g.Local = hostName, guestName, hostSide
+	if !host {
+		g.LocalName, g.PeerName, g.Local = guestName, hostName, misc.Opponent(hostSide)
+	}
+
+	return g, nil
+}
+
+// Host waits for a guest on a listener and offers it a game, the listener is
+// kept open to let the guest reconnect and is closed by Close
+func Host(ln net.Listener, offer RulesOffer, name string) (*Game, error) {
+
+	if _, _, err := offer.Options(name, ""); err != nil {
+		return nil, err
+	}
+
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		conn := NewConn(c)
+
+		g, err := hostHandshake(conn, offer, name)
+		if err == ErrRejected {
+			conn.Close()
+			return nil, err
+		}
+		if err != nil {
+			// the connection may be not a goblin at all, wait for another one
+			conn.Close()
+			continue
+		}
+
+		g.listener = ln
+		g.attach(conn)
+
+		go g.acceptReconnects()
+
+		return g, nil
+	}
+}
+
+func hostHandshake(conn *Conn, offer RulesOffer, name string) (*Game, error) {
+
+	hello, err := conn.expect("hello")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHello(hello); err != nil {
+		conn.Send(Message{Type: "error", Text: err.Error()})
+		return nil, err
+	}
+
+	g, err := newGame(offer, true, name, hello.Name)
+	if err != nil {
+		return nil, err
+	}
+	g.ID = g.Session.SessionID
+
+	conn.Send(Message{Type: "hello", Version: Version, Name: name, GameID: g.ID})
+	conn.Send(Message{Type: "rules", Rules: &offer})
+
+	reply, err := conn.Receive()
+	if err != nil {
+		return nil, err
+	}
+
+	switch reply.Type {
+	case "accept":
+		return g, nil
+	case "reject":
+		return nil, ErrRejected
+	}
+
+	return nil, fmt.Errorf("expected rules reply, got %s", reply.Type)
+}
+
+// Join connects to a host, accept decides whether to play by offered rules
+func Join(addr, name string, accept func(RulesOffer) bool) (*Game, error) {
+
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := NewConn(c)
+
+	g, err := joinHandshake(conn, name, accept)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	g.addr = addr
+	g.attach(conn)
+
+	return g, nil
+}
+
+func joinHandshake(conn *Conn, name string, accept func(RulesOffer) bool) (*Game, error) {
+
+	if err := conn.Send(Message{Type: "hello", Version: Version, Name: name}); err != nil {
+		return nil, err
+	}
+
+	hello, err := conn.expect("hello")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHello(hello); err != nil {
+		return nil, err
+	}
+
+	msg, err := conn.expect("rules")
+	if err != nil {
+		return nil, err
+	}
+	if msg.Rules == nil {
+		return nil, fmt.Errorf("no rules offered")
+	}
+
+	g, err := newGame(*msg.Rules, false, hello.Name, name)
+	if err != nil {
+		conn.Send(Message{Type: "reject", Text: err.Error()})
+		return nil, err
+	}
+
+	if !accept(*msg.Rules) {
+		conn.Send(Message{Type: "reject"})
+		return nil, ErrRejected
+	}
+
+	g.ID = hello.GameID
+
+	return g, conn.Send(Message{Type: "accept"})
+}
+
+// attach makes conn the current connection and starts serving it
+func (g *Game) attach(conn *Conn) {
+	g.mu.Lock()
+	g.conn = conn
+	g.mu.Unlock()
+	g.serve(conn)
+}
+
+// serve keeps conn alive and passes messages received from it to Messages,
+// local notifications sent by the peer are dropped
+func (g *Game) serve(conn *Conn) {
+
+	go conn.ping()
+
+	go func() {
+		for {
+			msg, err := conn.Receive()
+			if err != nil {
+				g.Messages <- Message{Type: "disconnected", Text: err.Error(), conn: conn}
+				return
+			}
+			if msg.Type == "connected" || msg.Type == "disconnected" {
+				continue
+			}
+			g.Messages <- msg
+		}
+	}()
+}
+
+// isClosed tells if the game has been closed locally
+func (g *Game) isClosed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.closed
+}
+
+// acceptReconnects lets the guest connect again to the host
+func (g *Game) acceptReconnects() {
+	for {
+		c, err := g.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		conn := NewConn(c)
+
+		hello, err := conn.expect("hello")
+		if err == nil {
+			err = checkHello(hello)
+		}
+		if err == nil && hello.GameID != g.ID {
+			err = fmt.Errorf("unknown game")
+		}
+		if err != nil {
+			conn.Send(Message{Type: "error", Text: err.Error()})
+			conn.Close()
+			continue
+		}
+
+		conn.Send(Message{Type: "hello", Version: Version, Name: g.LocalName, GameID: g.ID})
+
+		g.Messages <- Message{Type: "connected", conn: conn}
+	}
+}
+
+// reconnect dials the host until it succeeds or the game is closed
+func (g *Game) reconnect() {
+
+	for delay := 100 * time.Millisecond; !g.isClosed(); {
+
+		if c, err := net.Dial("tcp", g.addr); err == nil {
+
+			conn := NewConn(c)
+			conn.Send(Message{Type: "hello", Version: Version, Name: g.LocalName, GameID: g.ID})
+
+			hello, err := conn.expect("hello")
+			if err == nil {
+				err = checkHello(hello)
+			}
+			if err == nil {
+				g.Messages <- Message{Type: "connected", conn: conn}
+				return
+			}
+
+			conn.Close()
+		}
+
+		time.Sleep(delay)
+		if delay < 5*time.Second {
+			delay *= 2
+		}
+	}
+}
+
+// Connected tells if the peer is connected
+func (g *Game) Connected() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.conn != nil
+}
+
+func (g *Game) send(msg Message) error {
+
+	g.mu.Lock()
+	conn := g.conn
+	g.mu.Unlock()
+
+	if conn == nil {
+		return ErrDisconnected
+	}
+
+	return conn.Send(msg)
+}
+
+func (g *Game) notation() misc.Notation {
+	return misc.DefaultNotation(g.Session.Board.CellsVert)
+}
+
+// Play makes a local move
+func (g *Game) Play(pos misc.CellPosition) error {
+
+	if !g.Connected() {
+		return ErrDisconnected
+	}
+	if g.Session.Turn != g.Local && !g.Session.Over() {
+		return misc.ErrWrongTurn
+	}
+
+	seq := len(g.Session.History)
+
+	if err := g.Session.Play(pos); err != nil {
+		return err
+	}
+
+	return g.send(Message{Type: "move", Pos: g.notation().Format(pos), Seq: seq})
+}
+
+// RequestUndo asks the peer to take back the last move
+func (g *Game) RequestUndo() error {
+	if len(g.Session.History) == 0 {
+		return misc.ErrNoMoves
+	}
+	if err := g.send(Message{Type: "undo"}); err != nil {
+		return err
+	}
+	g.pending = true
+	return nil
+}
+
+// AnswerUndo accepts or declines undo request of the peer
+func (g *Game) AnswerUndo(accept bool) error {
+
+	if !g.UndoRequested {
+		return ErrNoUndo
+	}
+	g.UndoRequested = false
+
+	if accept {
+		if err := g.Session.Undo(); err != nil {
+			accept = false
+		}
+	}
+
+	return g.send(Message{Type: "undo_reply", Accept: accept})
+}
+
+// Resign gives up the game
+func (g *Game) Resign() error {
+	if err := g.Session.Resign(g.Local); err != nil {
+		return err
+	}
+	return g.send(Message{Type: "resign"})
+}
+
+// Chat sends a text message to the peer
+func (g *Game) Chat(text string) error {
+	return g.send(Message{Type: "chat", Text: text})
+}
+
+// Close says goodbye to the peer and closes connection
+func (g *Game) Close() {
+
+	g.send(Message{Type: "bye"})
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.closed = true
+
+	if g.conn != nil {
+		g.conn.Close()
+		g.conn = nil
+	}
+	if g.listener != nil {
+		g.listener.Close()
+	}
+}
+
+// syncMessage describes the whole game for a reconnected guest
+func (g *Game) syncMessage() Message {
+
+	msg := Message{Type: "sync", Moves: make([]string, len(g.Session.History))}
+
+	for i, move := range g.Session.History {
+		msg.Moves[i] = g.notation().Format(move.Pos)
+	}
+
+	if g.Session.Status == misc.GameResigned {
+		msg.Resigned = sideName(misc.Opponent(g.Session.Winner))
+	}
+
+	return msg
+}
+
+// applySync replays the game sent by the host
+func (g *Game) applySync(msg Message) error {
+
+	options, _, err := g.offer.Options(g.PeerName, g.LocalName)
+	if err != nil {
+		return err
+	}
+
+	session := misc.NewSession(options)
+	session.SessionID = g.Session.SessionID
+
+	for _, name := range msg.Moves {
+		pos, err := g.notation().Parse(name)
+		if err != nil {
+			return err
+		}
+		if err := session.Play(pos); err != nil {
+			return fmt.Errorf("move %s: %v", name, err)
+		}
+	}
+
+	if msg.Resigned != "" {
+		side, err := parseSide(msg.Resigned)
+		if err != nil {
+			return err
+		}
+		session.Resign(side)
+	}
+
+	g.Session = session
+	g.pending, g.UndoRequested = false, false
+
+	return nil
+}
+
+// resync makes the game state equal to the host's one
+func (g *Game) resync() {
+	if g.host {
+		g.send(g.syncMessage())
+	} else {
+		g.send(Message{Type: "sync_request"})
+	}
+}
+
+// Handle applies a message received from Messages and returns a notice to show to the user,
+// Session may be replaced by Handle
+func (g *Game) Handle(msg Message) string {
+
+	peer := misc.Opponent(g.Local)
+
+	switch msg.Type {
+
+	case "connected":
+		g.mu.Lock()
+		old := g.conn
+		closed := g.closed
+		if !closed {
+			g.conn = msg.conn
+		}
+		g.mu.Unlock()
+
+		if closed {
+			msg.conn.Close()
+			return ""
+		}
+		if old != nil && old != msg.conn {
+			old.Close()
+		}
+
+		g.serve(msg.conn)
+
+		if g.host {
+			g.send(g.syncMessage())
+		}
+		return g.PeerName + " has reconnected"
+
+	case "disconnected":
+		g.mu.Lock()
+		current := g.conn == msg.conn
+		if current {
+			g.conn = nil
+		}
+		closed := g.closed
+		g.mu.Unlock()
+
+		msg.conn.Close()
+
+		if !current || closed {
+			return ""
+		}
+		if !g.host {
+			go g.reconnect()
+		}
+		return g.PeerName + " is disconnected, waiting for reconnection"
+
+	case "move":
+		pos, err := g.notation().Parse(msg.Pos)
+		if err != nil {
+			g.send(Message{Type: "error", Text: err.Error()})
+			return ""
+		}
+		if msg.Seq != len(g.Session.History) || g.Session.Turn != peer {
+			g.resync()
+			return "Game is out of sync, restoring"
+		}
+		if err := g.Session.Play(pos); err != nil {
+			g.send(Message{Type: "error", Text: err.Error()})
+			return ""
+		}
+		return ""
+
+	case "undo":
+		g.UndoRequested = true
+		return g.PeerName + " asks to take back the last move, accept? (y/n)"
+
+	case "undo_reply":
+		if !g.pending {
+			return ""
+		}
+		g.pending = false
+		if !msg.Accept {
+			return g.PeerName + " has declined undo"
+		}
+		if g.Session.Undo() != nil {
+			g.resync()
+		}
+		return g.PeerName + " has accepted undo"
+
+	case "resign":
+		if g.Session.Resign(peer) != nil {
+			g.resync()
+			return ""
+		}
+		return g.PeerName + " has resigned"
+
+	case "chat":
+		return g.PeerName + ": " + msg.Text
+
+	case "sync":
+		// the host owns the game state, so it never takes one from the guest
+		if g.host {
+			return ""
+		}
+		if err := g.applySync(msg); err != nil {
+			return "Can't restore game: " + err.Error()
+		}
+		return ""
+
+	case "sync_request":
+		if g.host {
+			g.send(g.syncMessage())
+		}
+		return ""
+
+	case "bye":
+		g.mu.Lock()
+		g.closed = true
+		if g.conn != nil {
+			g.conn.Close()
+			g.conn = nil
+		}
+		g.mu.Unlock()
+		return g.PeerName + " has left the game"
+
+	case "error":
+		return g.PeerName + " reports error: " + msg.Text
+	}
+
+	return ""
+}
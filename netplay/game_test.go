@@ -0,0 +1,297 @@
+package netplay
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/risboo6909/goblin/misc"
+)
+
+var testOffer = RulesOffer{BoardSide: 7, WinLength: 4, Rules: "freestyle", FirstMove: "X", HostSide: "X"}
+
+func acceptAll(RulesOffer) bool {
+	return true
+}
+
+// connect starts a game between host and guest over loopback
+func connect(t *testing.T, offer RulesOffer) (host, guest *Game) {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		var err error
+		host, err = Host(ln, offer, "alice")
+		done <- err
+	}()
+
+	guest, err = Join(ln.Addr().String(), "bob", acceptAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	return host, guest
+}
+
+// receive waits for a message of a given type and handles it, returns the notice
+func receive(t *testing.T, g *Game, msgType string) string {
+	for {
+		select {
+		case msg := <-g.Messages:
+			notice := g.Handle(msg)
+			if msg.Type == msgType {
+				return notice
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: no %s message", g.LocalName, msgType)
+		}
+	}
+}
+
+func play(t *testing.T, from, to *Game, pos string) {
+	p, err := misc.DefaultNotation(7).Parse(pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := from.Play(p); err != nil {
+		t.Fatalf("%s plays %s: %v", from.LocalName, pos, err)
+	}
+	receive(t, to, "move")
+}
+
+func sameGame(t *testing.T, a, b *Game) {
+	if len(a.Session.History) != len(b.Session.History) {
+		t.Fatalf("histories differ: %v and %v", a.Session.History, b.Session.History)
+	}
+	for i := range a.Session.History {
+		assertEqual(t, a.Session.History[i], b.Session.History[i])
+	}
+	assertEqual(t, a.Session.Status, b.Session.Status)
+	assertEqual(t, a.Session.Winner, b.Session.Winner)
+}
+
+func assertEqual(t *testing.T, x, y interface{}) {
+	t.Helper()
+	if x != y {
+		t.Fatalf("%v != %v", x, y)
+	}
+}
+
+func TestGame(t *testing.T) {
+
+	host, guest := connect(t, testOffer)
+	defer host.Close()
+	defer guest.Close()
+
+	assertEqual(t, host.Local, misc.Cell(misc.X))
+	assertEqual(t, guest.Local, misc.Cell(misc.O))
+	assertEqual(t, host.PeerName, "bob")
+	assertEqual(t, guest.PeerName, "alice")
+	assertEqual(t, host.ID, guest.ID)
+
+	// guest can't move out of turn
+	assertEqual(t, guest.Play(misc.CellPosition{Col: 0, Row: 0}), error(misc.ErrWrongTurn))
+
+	play(t, host, guest, "A1")
+	play(t, guest, host, "A2")
+
+	guest.Chat("hi")
+	assertEqual(t, receive(t, host, "chat"), "bob: hi")
+
+	// declined and accepted undo
+	play(t, host, guest, "G7")
+	host.RequestUndo()
+	receive(t, guest, "undo")
+	guest.AnswerUndo(false)
+	assertEqual(t, receive(t, host, "undo_reply"), "bob has declined undo")
+	assertEqual(t, len(host.Session.History), 3)
+
+	host.RequestUndo()
+	receive(t, guest, "undo")
+	guest.AnswerUndo(true)
+	receive(t, host, "undo_reply")
+	assertEqual(t, len(host.Session.History), 2)
+	assertEqual(t, len(guest.Session.History), 2)
+	assertEqual(t, guest.AnswerUndo(true), ErrNoUndo)
+
+	for _, pos := range []string{"B1", "B2", "C1", "C2"} {
+		if host.Session.Turn == misc.X {
+			play(t, host, guest, pos)
+		} else {
+			play(t, guest, host, pos)
+		}
+	}
+
+	guest.Resign()
+	assertEqual(t, receive(t, host, "resign"), "bob has resigned")
+	assertEqual(t, host.Session.Status, misc.GameResigned)
+
+	sameGame(t, host, guest)
+	assertEqual(t, host.Session.Winner, misc.Cell(misc.X))
+}
+
+func TestGameWin(t *testing.T) {
+
+	offer := testOffer
+	offer.HostSide, offer.FirstMove = "O", "O"
+
+	host, guest := connect(t, offer)
+	defer host.Close()
+	defer guest.Close()
+
+	assertEqual(t, host.Local, misc.Cell(misc.O))
+
+	for i, pos := range []string{"A1", "A7", "B1", "B7", "C1", "C7", "D1"} {
+		if i%2 == 0 {
+			play(t, host, guest, pos)
+		} else {
+			play(t, guest, host, pos)
+		}
+	}
+
+	assertEqual(t, guest.Session.Status, misc.GameWon)
+	assertEqual(t, guest.Session.Winner, misc.Cell(misc.O))
+	assertEqual(t, len(guest.Session.Intervals), 1)
+	sameGame(t, host, guest)
+}
+
+func TestReconnect(t *testing.T) {
+
+	host, guest := connect(t, testOffer)
+	defer host.Close()
+	defer guest.Close()
+
+	play(t, host, guest, "D4")
+	play(t, guest, host, "E5")
+
+	// connection is lost
+	guest.mu.Lock()
+	guest.conn.Close()
+	guest.mu.Unlock()
+
+	assertEqual(t, receive(t, host, "disconnected"), "bob is disconnected, waiting for reconnection")
+	receive(t, guest, "disconnected")
+
+	assertEqual(t, host.Play(misc.CellPosition{Col: 0, Row: 0}), ErrDisconnected)
+
+	// guest reconnects by itself and the host sends the game to it
+	assertEqual(t, receive(t, guest, "connected"), "alice has reconnected")
+	assertEqual(t, receive(t, host, "connected"), "bob has reconnected")
+	receive(t, guest, "sync")
+
+	sameGame(t, host, guest)
+
+	play(t, host, guest, "C3")
+	play(t, guest, host, "F6")
+	sameGame(t, host, guest)
+}
+
+func TestOutOfSync(t *testing.T) {
+
+	host, guest := connect(t, testOffer)
+	defer host.Close()
+	defer guest.Close()
+
+	play(t, host, guest, "D4")
+	play(t, guest, host, "E5")
+
+	// guest has lost its moves somehow
+	guest.Session.Undo()
+	guest.Session.Undo()
+
+	host.Play(misc.CellPosition{Col: 2, Row: 2})
+
+	assertEqual(t, receive(t, guest, "move"), "Game is out of sync, restoring")
+	receive(t, host, "sync_request")
+	receive(t, guest, "sync")
+
+	sameGame(t, host, guest)
+	assertEqual(t, len(guest.Session.History), 3)
+}
+
+func TestSpoofedMessages(t *testing.T) {
+
+	host, guest := connect(t, testOffer)
+	defer host.Close()
+	defer guest.Close()
+
+	play(t, host, guest, "D4")
+
+	// local notifications sent by the peer are dropped, the guest can't replace
+	// the host's game
+	for _, msgType := range []string{"connected", "disconnected"} {
+		host.send(Message{Type: msgType})
+		guest.send(Message{Type: msgType})
+	}
+	guest.send(Message{Type: "sync"})
+
+	// the chat is received after the spoofed messages
+	guest.Chat("hi")
+	host.Chat("hello")
+
+	assertEqual(t, receive(t, host, "chat"), "bob: hi")
+	assertEqual(t, receive(t, guest, "chat"), "alice: hello")
+
+	assertEqual(t, len(host.Session.History), 1)
+	sameGame(t, host, guest)
+
+	play(t, guest, host, "E5")
+	sameGame(t, host, guest)
+}
+
+func TestRulesRejected(t *testing.T) {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	done := make(chan error)
+	go func() {
+		_, err := Host(ln, testOffer, "alice")
+		done <- err
+	}()
+
+	_, err = Join(ln.Addr().String(), "bob", func(offer RulesOffer) bool {
+		assertEqual(t, offer, testOffer)
+		return false
+	})
+
+	assertEqual(t, err, ErrRejected)
+	assertEqual(t, <-done, ErrRejected)
+}
+
+func TestVersionMismatch(t *testing.T) {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go Host(ln, testOffer, "alice")
+
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := NewConn(c)
+	defer conn.Close()
+
+	conn.Send(Message{Type: "hello", Version: Version + 1, Name: "bob"})
+
+	_, err = conn.expect("hello")
+	if err == nil || !strings.Contains(err.Error(), ErrVersion.Error()) {
+		t.Fatalf("expected version error, got %v", err)
+	}
+}
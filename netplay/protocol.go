@@ -0,0 +1,211 @@
+// Package netplay implements two-player games between terminals over TCP
+//
+// Peers exchange JSON messages, one per line. A guest connects to a host and
+// both send "hello" with protocol version, then the host offers game rules
+// and the guest replies with "accept" or "reject". After that peers send
+// "move", "undo" with "undo_reply", "resign", "chat" and "bye" messages.
+// If a guest reconnects after connection loss it says hello with game ID
+// and the host replies with "sync" carrying all moves made so far.
+package netplay
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/risboo6909/goblin/misc"
+)
+
+// Version is a protocol version, peers with different versions can't play
+const Version = 1
+
+// PingInterval is how often peers ping each other, a peer silent for three
+// intervals is disconnected
+var PingInterval = 5 * time.Second
+
+// RulesOffer describes a game proposed by the host
+type RulesOffer struct {
+	BoardSide int    `json:"board_side"`
+	WinLength int    `json:"win_length"`
+	Rules     string `json:"rules"`
+	FirstMove string `json:"first_move"`
+	HostSide  string `json:"host_side"`
+}
+
+// Message is a protocol message
+type Message struct {
+	Type string `json:"type"`
+
+	// hello
+	Version int    `json:"version,omitempty"`
+	Name    string `json:"name,omitempty"`
+	GameID  string `json:"game_id,omitempty"`
+
+	// rules offer
+	Rules *RulesOffer `json:"rules,omitempty"`
+
+	// move, seq is the number of moves made before it
+	Pos string `json:"pos,omitempty"`
+	Seq int    `json:"seq,omitempty"`
+
+	// undo_reply and rules reply
+	Accept bool `json:"accept,omitempty"`
+
+	// chat, error and reject reason
+	Text string `json:"text,omitempty"`
+
+	// sync, the side which has resigned if any
+	Moves    []string `json:"moves,omitempty"`
+	Resigned string   `json:"resigned,omitempty"`
+
+	// connection which has been established or lost, for local
+	// "connected" and "disconnected" messages
+	conn *Conn
+}
+
+// Errors returned by netplay
+var (
+	ErrVersion      = errors.New("incompatible protocol version")
+	ErrRejected     = errors.New("rules rejected")
+	ErrDisconnected = errors.New("opponent is disconnected")
+	ErrNoUndo       = errors.New("no undo request to answer")
+)
+
+// Conn is a connection to a peer
+type Conn struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewConn wraps a network connection
+func NewConn(conn net.Conn) *Conn {
+	return &Conn{conn: conn, scanner: bufio.NewScanner(conn), enc: json.NewEncoder(conn)}
+}
+
+// Send sends a message, it is safe for concurrent use
+func (c *Conn) Send(msg Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(3 * PingInterval))
+	return c.enc.Encode(msg)
+}
+
+// Receive waits for the next message skipping pings
+func (c *Conn) Receive() (Message, error) {
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(3 * PingInterval))
+
+		if !c.scanner.Scan() {
+			if err := c.scanner.Err(); err != nil {
+				return Message{}, err
+			}
+			return Message{}, fmt.Errorf("connection closed")
+		}
+
+		var msg Message
+		if err := json.Unmarshal(c.scanner.Bytes(), &msg); err != nil {
+			return Message{}, fmt.Errorf("malformed message: %v", err)
+		}
+
+		if msg.Type != "ping" {
+			return msg, nil
+		}
+	}
+}
+
+// expect receives a message of a given type, error message from the peer is returned as error
+func (c *Conn) expect(msgType string) (Message, error) {
+	msg, err := c.Receive()
+	if err != nil {
+		return msg, err
+	}
+	if msg.Type == "error" {
+		return msg, fmt.Errorf("peer: %s", msg.Text)
+	}
+	if msg.Type != msgType {
+		return msg, fmt.Errorf("expected %s, got %s", msgType, msg.Type)
+	}
+	return msg, nil
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// ping keeps the connection alive until it is closed
+func (c *Conn) ping() {
+	for {
+		time.Sleep(PingInterval)
+		if c.Send(Message{Type: "ping"}) != nil {
+			return
+		}
+	}
+}
+
+// checkHello checks peer's hello message
+func checkHello(msg Message) error {
+	if msg.Version != Version {
+		return fmt.Errorf("%v: peer has %d, we have %d", ErrVersion, msg.Version, Version)
+	}
+	return nil
+}
+
+// sideName converts a side into protocol form
+func sideName(side misc.Cell) string {
+	return string(side)
+}
+
+func parseSide(name string) (misc.Cell, error) {
+	switch name {
+	case "X":
+		return misc.X, nil
+	case "O":
+		return misc.O, nil
+	}
+	return misc.E, fmt.Errorf("unknown side %q", name)
+}
+
+// Options converts offer into session options, host side is played by hostName
+func (offer RulesOffer) Options(hostName, guestName string) (misc.SessionOptions, misc.Cell, error) {
+
+	options := misc.SessionOptions{BoardSide: offer.BoardSide, WinLength: offer.WinLength}
+
+	if offer.BoardSide < 3 || offer.BoardSide > misc.MaxBoardSide || offer.WinLength < 3 || offer.WinLength > offer.BoardSide {
+		return options, misc.E, fmt.Errorf("invalid board %dx%d, %d in a row", offer.BoardSide, offer.BoardSide, offer.WinLength)
+	}
+
+	var err error
+
+	if options.Rules, err = misc.ParseRules(offer.Rules); err != nil {
+		return options, misc.E, err
+	}
+	if options.FirstMove, err = parseSide(offer.FirstMove); err != nil {
+		return options, misc.E, err
+	}
+
+	hostSide, err := parseSide(offer.HostSide)
+	if err != nil {
+		return options, misc.E, err
+	}
+
+	options.PlayerX, options.PlayerO = misc.HumanPlayer(hostName), misc.HumanPlayer(guestName)
+	if hostSide == misc.O {
+		options.PlayerX, options.PlayerO = options.PlayerO, options.PlayerX
+	}
+
+	return options, hostSide, nil
+}
+
+// String describes offer for a guest
+func (offer RulesOffer) String() string {
+	return fmt.Sprintf("%dx%d board, %d in a row, %s rules, %s moves first, host plays %s",
+		offer.BoardSide, offer.BoardSide, offer.WinLength, offer.Rules, offer.FirstMove, offer.HostSide)
+}
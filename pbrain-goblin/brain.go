@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/risboo6909/goblin/misc"
+)
+
+const (
+	// Gomocup games are always five in a row
+	winLength = 5
+
+	aboutInfo = `name="goblin", version="0.1", author="risboo6909", country="Russia"`
+
+	// share of a turn timeout kept as a safety margin
+	safetyMargin = 10
+
+	// number of moves remaining match time is spread over
+	movesToGo = 25
+
+	// turn timeout used until manager sends its limits
+	defaultTimeoutTurn = 5 * time.Second
+
+	// the least time given to a move
+	minTimeBudget = time.Millisecond
+)
+
+// brain stones are always X and opponent's are O, colors don't matter for the protocol
+const (
+	own      = misc.X
+	opponent = misc.O
+)
+
+type brain struct {
+	out   io.Writer
+	board *misc.BoardDescription
+
+	// limits sent by INFO, zero means no limit
+	timeoutTurn  time.Duration
+	timeoutMatch time.Duration
+	timeLeft     time.Duration
+	maxMemory    int
+
+	// true once the manager has sent timeout_turn, zero turn timeout from
+	// the manager means playing as fast as possible
+	timeoutTurnSet bool
+
+	// engine search depth
+	maxDepth int
+}
+
+func newBrain(out io.Writer) *brain {
+	return &brain{out: out, maxDepth: misc.DefaultDepth, timeoutTurn: defaultTimeoutTurn}
+}
+
+func (b *brain) reply(format string, args ...interface{}) {
+	fmt.Fprintf(b.out, format+"\r\n", args...)
+}
+
+// run reads commands until END or end of input
+func (b *brain) run(in io.Reader) {
+
+	scanner := bufio.NewScanner(in)
+
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		command, args := strings.ToUpper(fields[0]), strings.TrimSpace(line[len(fields[0]):])
+
+		switch command {
+		case "START":
+			b.start(args)
+		case "RECTSTART":
+			b.rectStart(args)
+		case "RESTART":
+			b.restart()
+		case "BEGIN":
+			b.begin()
+		case "TURN":
+			b.turn(args)
+		case "BOARD":
+			b.boardCommand(scanner)
+		case "TAKEBACK":
+			b.takeBack(args)
+		case "INFO":
+			b.info(args)
+		case "ABOUT":
+			b.reply(aboutInfo)
+		case "END":
+			return
+		default:
+			b.reply("UNKNOWN command %s", command)
+		}
+	}
+}
+
+// parseCoords parses "x,y" pair and checks it is on the board
+func (b *brain) parseCoords(s string) (misc.CellPosition, error) {
+
+	fields := strings.Split(s, ",")
+	if len(fields) < 2 {
+		return misc.CellPosition{}, fmt.Errorf("malformed coordinates %q", s)
+	}
+
+	x, errX := strconv.Atoi(strings.TrimSpace(fields[0]))
+	y, errY := strconv.Atoi(strings.TrimSpace(fields[1]))
+	if errX != nil || errY != nil {
+		return misc.CellPosition{}, fmt.Errorf("malformed coordinates %q", s)
+	}
+
+	if _, err := b.board.ToLinear(x, y); err != nil {
+		return misc.CellPosition{}, fmt.Errorf("coordinates %q are out of the board", s)
+	}
+
+	return misc.CellPosition{Col: x, Row: y}, nil
+}
+
+func (b *brain) started() bool {
+	if b.board == nil {
+		b.reply("ERROR game has not been started")
+		return false
+	}
+	return true
+}
+
+func (b *brain) start(args string) {
+	size, err := strconv.Atoi(args)
+	if err != nil || size < winLength || size > misc.MaxBoardSide {
+		b.reply("ERROR unsupported board size %s", args)
+		return
+	}
+	b.board = misc.NewBoard(size, size)
+	b.reply("OK")
+}
+
+func (b *brain) rectStart(args string) {
+	fields := strings.Split(args, ",")
+	if len(fields) != 2 || strings.TrimSpace(fields[0]) != strings.TrimSpace(fields[1]) {
+		b.reply("ERROR only square boards are supported")
+		return
+	}
+	b.start(strings.TrimSpace(fields[0]))
+}
+
+func (b *brain) restart() {
+	if b.started() {
+		b.board = misc.NewBoard(b.board.CellsHoriz, b.board.CellsVert)
+		b.reply("OK")
+	}
+}
+
+func (b *brain) begin() {
+	if b.started() {
+		b.move()
+	}
+}
+
+func (b *brain) turn(args string) {
+
+	if !b.started() {
+		return
+	}
+
+	pos, err := b.parseCoords(args)
+	if err != nil {
+		b.reply("ERROR %v", err)
+		return
+	}
+
+	if b.board.GetCell(pos.Col, pos.Row) != misc.E {
+		b.reply("ERROR cell %s is occupied", args)
+		return
+	}
+
+	b.board.SetCell(pos.Col, pos.Row, opponent)
+	b.move()
+}
+
+// boardCommand reads position sent by BOARD until DONE line
+func (b *brain) boardCommand(scanner *bufio.Scanner) {
+
+	if !b.started() {
+		return
+	}
+
+	board := misc.NewBoard(b.board.CellsHoriz, b.board.CellsVert)
+	var err error
+
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.ToUpper(line) == "DONE" {
+			if err != nil {
+				b.reply("ERROR %v", err)
+				return
+			}
+			b.board = board
+			b.move()
+			return
+		}
+
+		pos, parseErr := b.parseCoords(line)
+		fields := strings.Split(line, ",")
+
+		if parseErr != nil || len(fields) != 3 {
+			err = fmt.Errorf("malformed stone %q", line)
+			continue
+		}
+
+		// 1 - own stone, 2 - opponent's one, 3 - winning line in continuous games
+		switch strings.TrimSpace(fields[2]) {
+		case "1":
+			board.SetCell(pos.Col, pos.Row, own)
+		case "2", "3":
+			board.SetCell(pos.Col, pos.Row, opponent)
+		default:
+			err = fmt.Errorf("malformed stone %q", line)
+		}
+	}
+}
+
+func (b *brain) takeBack(args string) {
+
+	if !b.started() {
+		return
+	}
+
+	pos, err := b.parseCoords(args)
+	if err != nil {
+		b.reply("ERROR %v", err)
+		return
+	}
+
+	b.board.SetCell(pos.Col, pos.Row, misc.E)
+	b.reply("OK")
+}
+
+// info stores limits, INFO commands have no reply
+func (b *brain) info(args string) {
+
+	fields := strings.Fields(args)
+	if len(fields) != 2 {
+		return
+	}
+
+	value, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "timeout_turn":
+		b.timeoutTurn = time.Duration(value) * time.Millisecond
+		b.timeoutTurnSet = true
+	case "timeout_match":
+		b.timeoutMatch = time.Duration(value) * time.Millisecond
+	case "time_left":
+		b.timeLeft = time.Duration(value) * time.Millisecond
+	case "max_memory":
+		b.maxMemory = value
+	}
+}
+
+// timeBudget computes time for the next move from turn and match limits
+func (b *brain) timeBudget() time.Duration {
+
+	if b.timeoutTurnSet && b.timeoutTurn <= 0 {
+		return minTimeBudget
+	}
+
+	budget := b.timeoutTurn
+
+	if b.timeoutMatch > 0 && b.timeLeft > 0 {
+		share := b.timeLeft / movesToGo
+		if budget == 0 || share < budget {
+			budget = share
+		}
+	}
+
+	budget -= budget / safetyMargin
+
+	// a budget eaten up by the safety margin still leaves time for a move
+	if budget <= 0 && (b.timeoutTurn > 0 || b.timeoutMatch > 0 || b.timeLeft > 0) {
+		budget = minTimeBudget
+	}
+
+	return budget
+}
+
+// move lets engine choose a move, makes it and reports it to the manager
+func (b *brain) move() {
+
+	options := misc.NewAIOptions(own, winLength, b.maxDepth)
+	options.TimeLimit = b.timeBudget()
+
+	pos, err := misc.BestMove(b.board, options)
+	if err != nil {
+		b.reply("ERROR %v", err)
+		return
+	}
+
+	b.board.SetCell(pos.Col, pos.Row, own)
+	b.reply("%d,%d", pos.Col, pos.Row)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// play feeds a transcript to a new brain and returns its replies, brain is
+// depth limited and untimed unless the transcript sets its limits
+func play(t *testing.T, transcript string) []string {
+
+	var out bytes.Buffer
+
+	b := newBrain(&out)
+	b.maxDepth, b.timeoutTurn = 2, 0
+	b.run(strings.NewReader(transcript))
+
+	replies := strings.Split(strings.TrimSuffix(out.String(), "\r\n"), "\r\n")
+	if out.Len() == 0 {
+		replies = []string{}
+	}
+
+	return replies
+}
+
+func expectReplies(t *testing.T, transcript string, expected ...string) []string {
+
+	replies := play(t, transcript)
+
+	if len(replies) != len(expected) {
+		t.Fatalf("Expected %d replies, got %q", len(expected), replies)
+	}
+
+	for i, reply := range replies {
+		// "*" matches any move
+		if expected[i] == "*" {
+			if _, err := parseMove(reply); err != nil {
+				t.Fatalf("Reply %q is not a move", reply)
+			}
+			continue
+		}
+		if reply != expected[i] {
+			t.Fatalf("Expected %q, got %q", expected[i], reply)
+		}
+	}
+
+	return replies
+}
+
+func parseMove(reply string) ([2]int, error) {
+	var move [2]int
+	fields := strings.Split(reply, ",")
+	if len(fields) != 2 {
+		return move, strconv.ErrSyntax
+	}
+	for i, field := range fields {
+		value, err := strconv.Atoi(field)
+		if err != nil {
+			return move, err
+		}
+		move[i] = value
+	}
+	return move, nil
+}
+
+func TestBrainStart(t *testing.T) {
+
+	expectReplies(t, "START 20\nABOUT\nEND\nSTART 20\n", "OK", aboutInfo)
+	expectReplies(t, "START 2\nSTART abc\nSTART 100000\nRECTSTART 20,15\nRECTSTART 15,15\n",
+		"ERROR unsupported board size 2", "ERROR unsupported board size abc", "ERROR unsupported board size 100000",
+		"ERROR only square boards are supported", "OK")
+	expectReplies(t, "BEGIN\nTURN 1,1\nFOO\n",
+		"ERROR game has not been started", "ERROR game has not been started", "UNKNOWN command FOO")
+}
+
+func TestBrainBegin(t *testing.T) {
+	// brain opens in the center
+	expectReplies(t, "START 15\nINFO timeout_turn 1000\nBEGIN\n", "OK", "7,7")
+	expectReplies(t, "START 20\nBEGIN\nRESTART\nBEGIN\nEND\n", "OK", "10,10", "OK", "10,10")
+}
+
+func TestBrainTurn(t *testing.T) {
+
+	replies := expectReplies(t, "START 15\nINFO timeout_turn 300\nTURN 7,7\nTURN 0,0\nTURN 20,20\nTURN 0,0\nTURN x,y\n",
+		"OK", "8,6", "*", "ERROR coordinates \"20,20\" are out of the board",
+		"ERROR cell 0,0 is occupied", "ERROR malformed coordinates \"x,y\"")
+
+	move, _ := parseMove(replies[2])
+	if move == [2]int{0, 0} || move == [2]int{7, 7} || move == [2]int{8, 6} {
+		t.Fatalf("Brain moved to occupied cell %v", move)
+	}
+}
+
+func TestBrainBoard(t *testing.T) {
+
+	// brain has four in a row and must complete five
+	transcript := `START 15
+BOARD
+3,3,1
+4,3,1
+5,3,1
+6,3,1
+3,5,2
+4,5,2
+5,5,2
+6,5,2
+DONE
+END
+`
+	replies := expectReplies(t, transcript, "OK", "*")
+	if replies[1] != "2,3" && replies[1] != "7,3" {
+		t.Fatalf("Brain missed the win, played %v", replies[1])
+	}
+
+	expectReplies(t, "START 15\nBOARD\n1,1,1\n1,2,5\nDONE\n", "OK", `ERROR malformed stone "1,2,5"`)
+}
+
+func TestBrainTakeBack(t *testing.T) {
+	expectReplies(t, "START 15\nBEGIN\nTAKEBACK 7,7\nBEGIN\n", "OK", "7,7", "OK", "7,7")
+}
+
+func TestBrainTimeLimits(t *testing.T) {
+
+	b := newBrain(&bytes.Buffer{})
+	b.run(strings.NewReader("INFO timeout_turn 2000\nINFO timeout_match 100000\nINFO time_left 25000\nINFO max_memory 83886080\n"))
+
+	if b.timeoutTurn != 2*time.Second || b.timeLeft != 25*time.Second || b.maxMemory != 83886080 {
+		t.Fatalf("INFO has not been applied")
+	}
+
+	// match time is spread over remaining moves
+	if budget := b.timeBudget(); budget != 900*time.Millisecond {
+		t.Fatalf("Unexpected time budget %v", budget)
+	}
+
+	b.run(strings.NewReader("INFO time_left 100000\n"))
+	if budget := b.timeBudget(); budget != 1800*time.Millisecond {
+		t.Fatalf("Unexpected time budget %v", budget)
+	}
+
+	// zero turn timeout means playing as fast as possible, with or without match time
+	b.run(strings.NewReader("INFO timeout_turn 0\n"))
+	if budget := b.timeBudget(); budget != minTimeBudget {
+		t.Fatalf("Unexpected time budget %v", budget)
+	}
+
+	b = newBrain(&bytes.Buffer{})
+	b.run(strings.NewReader("INFO timeout_turn 0\n"))
+	if budget := b.timeBudget(); budget != minTimeBudget {
+		t.Fatalf("Unexpected time budget %v", budget)
+	}
+
+	// a move is made in time
+	b = newBrain(&bytes.Buffer{})
+	start := time.Now()
+	b.run(strings.NewReader("START 20\nINFO timeout_turn 500\nTURN 10,10\nTURN 11,11\n"))
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Brain has exceeded time limits: %v", elapsed)
+	}
+}
@@ -0,0 +1,11 @@
+// pbrain-goblin is a Piskvork (Gomocup) brain, it talks to a manager using
+// Piskvork brain protocol on stdin and stdout
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	newBrain(os.Stdout).run(os.Stdin)
+}
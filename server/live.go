@@ -0,0 +1,374 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/risboo6909/goblin/misc"
+)
+
+// Live play over WebSocket
+//
+// A client connects to /sessions/{id}/ws, optionally with "seat=X" or "seat=O"
+// to play for a human side, clients without a seat are spectators. A side
+// can be taken by one client at a time.
+//
+// Server sends events as JSON objects with "type" field:
+//
+//	state      full session state, sent on connect and after undo
+//	move       a move has been made
+//	thinking   engine is searching for a move
+//	game_over  game has finished, with winning intervals if there are any
+//	error      a command of this client has failed
+//
+// Seated clients send commands {"type":"move","pos":"H8"}, {"type":"undo"}
+// and {"type":"resign"}, engine replies are made and pushed automatically.
+
+// Event is a message sent to live clients
+type Event struct {
+	Type     string         `json:"type"`
+	State    *SessionState  `json:"state,omitempty"`
+	Move     *MoveState     `json:"move,omitempty"`
+	Thinking *ThinkingState `json:"thinking,omitempty"`
+	Result   *ResultState   `json:"result,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// ThinkingState describes progress of engine search
+type ThinkingState struct {
+	Player    string `json:"player"`
+	ElapsedMS int64  `json:"elapsed_ms"`
+
+	// completed search depth and the best move found so far, empty
+	// while there is no estimate yet
+	Depth int    `json:"depth,omitempty"`
+	Best  string `json:"best,omitempty"`
+	Score int    `json:"score,omitempty"`
+}
+
+// IntervalState is a winning sequence of cells
+type IntervalState struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ResultState is a result of a finished game
+type ResultState struct {
+	Status    string          `json:"status"`
+	Winner    string          `json:"winner,omitempty"`
+	Intervals []IntervalState `json:"intervals,omitempty"`
+}
+
+// Command is a message sent by a seated live client
+type Command struct {
+	Type string `json:"type"`
+	Pos  string `json:"pos,omitempty"`
+}
+
+// size of a client's outgoing queue, clients which can't keep up are disconnected
+const sendQueueLen = 64
+
+type client struct {
+	seat misc.Cell
+	send chan Event
+}
+
+// room keeps live clients of a session
+type room struct {
+	mu      sync.Mutex
+	clients map[*client]bool
+	seats   map[misc.Cell]*client
+}
+
+func stateEvent(s *misc.Session) Event {
+	state := State(s)
+	return Event{Type: "state", State: &state}
+}
+
+func moveEvent(s *misc.Session, move misc.Move) Event {
+	notation := misc.DefaultNotation(s.Board.CellsVert)
+	return Event{Type: "move", Move: &MoveState{notation.Format(move.Pos), cellName(move.Player)}}
+}
+
+func gameOverEvent(s *misc.Session) Event {
+
+	notation := misc.DefaultNotation(s.Board.CellsVert)
+	result := &ResultState{Status: s.Status.String(), Winner: cellName(s.Winner)}
+
+	for _, interval := range s.Intervals {
+		result.Intervals = append(result.Intervals,
+			IntervalState{notation.Format(interval.From), notation.Format(interval.To)})
+	}
+
+	return Event{Type: "game_over", Result: result}
+}
+
+func thinkingEvent(s *misc.Session, info misc.SearchInfo) Event {
+	return Event{Type: "thinking", Thinking: &ThinkingState{Player: cellName(s.Turn),
+		ElapsedMS: int64(info.Elapsed / time.Millisecond), Depth: info.Depth,
+		Best: misc.DefaultNotation(s.Board.CellsVert).Format(info.Best), Score: info.Score}}
+}
+
+// broadcast sends event to all clients of a room
+func (r *room) broadcast(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for c := range r.clients {
+		r.sendLocked(c, event)
+	}
+}
+
+// send sends event to a single client
+func (r *room) send(c *client, event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.clients[c] {
+		r.sendLocked(c, event)
+	}
+}
+
+func (r *room) sendLocked(c *client, event Event) {
+	select {
+	case c.send <- event:
+	default:
+		// client is too slow, drop it
+		r.removeLocked(c)
+	}
+}
+
+func (r *room) removeLocked(c *client) {
+	if !r.clients[c] {
+		return
+	}
+	delete(r.clients, c)
+	if r.seats[c.seat] == c {
+		delete(r.seats, c.seat)
+	}
+	close(c.send)
+}
+
+// changed pushes changes of a session made since it had a given number of moves
+func (r *room) changed(s *misc.Session, history int, wasOver bool) {
+
+	if len(s.History) >= history {
+		for _, move := range s.History[history:] {
+			r.broadcast(moveEvent(s, move))
+		}
+	} else {
+		r.broadcast(stateEvent(s))
+	}
+
+	if s.Over() && !wasOver {
+		r.broadcast(gameOverEvent(s))
+	}
+}
+
+// findRoom returns a room of a session or nil if nobody is connected
+func (srv *Server) findRoom(id string) *room {
+	srv.roomsMu.Lock()
+	defer srv.roomsMu.Unlock()
+	return srv.rooms[id]
+}
+
+// join adds a client to a session room, returns false if client's seat is taken
+func (srv *Server) join(id string, c *client) bool {
+
+	srv.roomsMu.Lock()
+	defer srv.roomsMu.Unlock()
+
+	r := srv.rooms[id]
+	if r == nil {
+		r = &room{clients: make(map[*client]bool), seats: make(map[misc.Cell]*client)}
+		srv.rooms[id] = r
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c.seat != misc.E {
+		if r.seats[c.seat] != nil {
+			return false
+		}
+		r.seats[c.seat] = c
+	}
+
+	r.clients[c] = true
+
+	return true
+}
+
+// leave removes a client from a session room, the room is deleted when it is empty
+func (srv *Server) leave(id string, c *client) {
+
+	srv.roomsMu.Lock()
+	defer srv.roomsMu.Unlock()
+
+	r := srv.rooms[id]
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.removeLocked(c)
+
+	if len(r.clients) == 0 {
+		delete(srv.rooms, id)
+	}
+}
+
+// thinking tells live clients about engine thinking time until returned stop function is called
+func (srv *Server) thinking(s *misc.Session) (stop func()) {
+
+	start, player := time.Now(), cellName(s.Turn)
+	done := make(chan struct{})
+
+	notify := func() {
+		if r := srv.findRoom(s.SessionID); r != nil {
+			r.broadcast(Event{Type: "thinking", Thinking: &ThinkingState{Player: player,
+				ElapsedMS: int64(time.Since(start) / time.Millisecond)}})
+		}
+	}
+
+	notify()
+
+	if srv.ThinkingInterval <= 0 {
+		return func() {}
+	}
+
+	go func() {
+		ticker := time.NewTicker(srv.ThinkingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				notify()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// live serves WebSocket connection of a player or a spectator
+func (srv *Server) live(w http.ResponseWriter, r *http.Request, id string) error {
+
+	c := &client{seat: misc.E, send: make(chan Event, sendQueueLen)}
+
+	if name := r.URL.Query().Get("seat"); name != "" {
+		var err error
+		if c.seat, err = parseSide(name); err != nil {
+			return err
+		}
+	}
+
+	// client joins under session lock, so it gets the current state before any later event
+	err := srv.Manager.With(id, func(s *misc.Session) error {
+		if c.seat != misc.E && s.Player(c.seat).Kind != misc.Human {
+			return &httpError{http.StatusConflict, "side " + cellName(c.seat) + " is played by engine"}
+		}
+		if !srv.join(id, c) {
+			return &httpError{http.StatusConflict, "side " + cellName(c.seat) + " is taken"}
+		}
+		c.send <- stateEvent(s)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	defer srv.leave(id, c)
+
+	conn, err := srv.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// upgrader has already replied
+		return nil
+	}
+
+	go func() {
+		for event := range c.send {
+			if conn.WriteJSON(event) != nil {
+				break
+			}
+		}
+		conn.Close()
+	}()
+
+	for {
+		var cmd Command
+		if err := conn.ReadJSON(&cmd); err != nil {
+			if !isJSONError(err) {
+				// connection is closed
+				return nil
+			}
+			srv.reply(id, c, badRequest("malformed command: %v", err))
+			continue
+		}
+		srv.reply(id, c, srv.command(id, c, cmd))
+	}
+}
+
+// isJSONError tells if reading failed because of bad message contents rather than connection
+func isJSONError(err error) bool {
+	switch err.(type) {
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		return true
+	}
+	return false
+}
+
+// reply sends command error to a client
+func (srv *Server) reply(id string, c *client, err error) {
+	if err == nil {
+		return
+	}
+	if r := srv.findRoom(id); r != nil {
+		r.send(c, Event{Type: "error", Error: err.Error()})
+	}
+}
+
+// command executes a command of a live client
+func (srv *Server) command(id string, c *client, cmd Command) error {
+
+	if c.seat == misc.E {
+		return &httpError{http.StatusForbidden, "spectators can't make moves"}
+	}
+
+	return srv.Manager.With(id, func(s *misc.Session) error {
+
+		var err error
+
+		switch cmd.Type {
+
+		case "move":
+			var pos misc.CellPosition
+			if pos, err = misc.DefaultNotation(s.Board.CellsVert).Parse(cmd.Pos); err != nil {
+				return badRequest("%v", err)
+			}
+			if s.Turn != c.seat && !s.Over() {
+				return misc.ErrWrongTurn
+			}
+			err = srv.apply(s, func() error { return s.Play(pos) })
+
+		case "undo":
+			err = srv.apply(s, func() error { return undo(s) })
+
+		case "resign":
+			err = srv.apply(s, func() error { return s.Resign(c.seat) })
+
+		default:
+			return badRequest("unknown command %q", cmd.Type)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return srv.think(s)
+	})
+}
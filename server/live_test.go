@@ -0,0 +1,177 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/risboo6909/goblin/misc"
+)
+
+// standInEngine quickly takes the first free cell of the bottom row
+func standInEngine(s *misc.Session, progress func(misc.SearchInfo)) error {
+	row := s.Board.CellsVert - 1
+	for col := 0; col < s.Board.CellsHoriz; col++ {
+		if s.Board.GetCell(col, row) == misc.E {
+			pos := misc.CellPosition{Col: col, Row: row}
+			progress(misc.SearchInfo{Depth: 1, Best: pos})
+			return s.PlayAs(s.Turn, pos)
+		}
+	}
+	return misc.ErrNoMoves
+}
+
+func newLiveServer() *httptest.Server {
+	srv := New(misc.NewManager(time.Hour), 0)
+	srv.Engine = standInEngine
+	srv.ThinkingInterval = time.Millisecond
+	return httptest.NewServer(srv)
+}
+
+func dial(t *testing.T, ts *httptest.Server, id, seat string) (*websocket.Conn, *http.Response, error) {
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/sessions/" + id + "/ws"
+	if seat != "" {
+		url += "?seat=" + seat
+	}
+	return websocket.DefaultDialer.Dial(url, nil)
+}
+
+// next reads events skipping thinking progress
+func next(t *testing.T, conn *websocket.Conn) Event {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var event Event
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Type != "thinking" {
+			return event
+		}
+	}
+}
+
+func TestLiveGame(t *testing.T) {
+
+	ts := newLiveServer()
+	defer ts.Close()
+
+	var state SessionState
+	call(t, "POST", ts.URL+"/sessions", CreateRequest{BoardSide: 7, WinLength: 4}, &state)
+
+	player, _, err := dial(t, ts, state.ID, "X")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer player.Close()
+
+	spectator, _, err := dial(t, ts, state.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spectator.Close()
+
+	for _, conn := range []*websocket.Conn{player, spectator} {
+		if event := next(t, conn); event.Type != "state" || event.State.ID != state.ID {
+			t.Fatalf("expected initial state, got %+v", event)
+		}
+	}
+
+	// side X is taken and O is played by engine
+	for _, seat := range []string{"X", "O"} {
+		if _, resp, err := dial(t, ts, state.ID, seat); err == nil || resp.StatusCode != http.StatusConflict {
+			t.Errorf("seat %s: expected conflict, got %v", seat, err)
+		}
+	}
+
+	// spectators can't move
+	spectator.WriteJSON(Command{Type: "move", Pos: "A1"})
+	if event := next(t, spectator); event.Type != "error" {
+		t.Fatalf("expected error, got %+v", event)
+	}
+
+	thinking := false
+
+	for i, pos := range []string{"A1", "B1", "C1", "D1"} {
+
+		if err := player.WriteJSON(Command{Type: "move", Pos: pos}); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, conn := range []*websocket.Conn{player, spectator} {
+
+			if event := next(t, conn); event.Type != "move" || *event.Move != (MoveState{pos, "X"}) {
+				t.Fatalf("move %s: got %+v", pos, event)
+			}
+
+			if i == 3 {
+				continue
+			}
+
+			// engine reply with thinking progress before it
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			for {
+				var event Event
+				if err := conn.ReadJSON(&event); err != nil {
+					t.Fatal(err)
+				}
+				if event.Type == "thinking" {
+					thinking = thinking || event.Thinking.Player == "O"
+					continue
+				}
+				if event.Type != "move" || event.Move.Player != "O" || !strings.HasSuffix(event.Move.Pos, "7") {
+					t.Fatalf("engine reply to %s: got %+v", pos, event)
+				}
+				break
+			}
+		}
+	}
+
+	if !thinking {
+		t.Error("no thinking progress received")
+	}
+
+	for _, conn := range []*websocket.Conn{player, spectator} {
+		event := next(t, conn)
+		if event.Type != "game_over" || event.Result.Winner != "X" || len(event.Result.Intervals) != 1 {
+			t.Fatalf("expected game over, got %+v", event)
+		}
+		if interval := event.Result.Intervals[0]; interval != (IntervalState{"A1", "D1"}) {
+			t.Fatalf("unexpected winning interval %+v", interval)
+		}
+	}
+
+	player.WriteJSON(Command{Type: "move", Pos: "E1"})
+	if event := next(t, player); event.Type != "error" {
+		t.Fatalf("expected error after game over, got %+v", event)
+	}
+}
+
+func TestLiveRESTMovesArePushed(t *testing.T) {
+
+	ts := newLiveServer()
+	defer ts.Close()
+
+	var state SessionState
+	call(t, "POST", ts.URL+"/sessions", CreateRequest{BoardSide: 7, WinLength: 4, Human: "both"}, &state)
+
+	spectator, _, err := dial(t, ts, state.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spectator.Close()
+
+	next(t, spectator)
+
+	call(t, "POST", ts.URL+"/sessions/"+state.ID+"/moves", MoveRequest{"C3"}, &state)
+	if event := next(t, spectator); event.Type != "move" || *event.Move != (MoveState{"C3", "X"}) {
+		t.Fatalf("expected move, got %+v", event)
+	}
+
+	call(t, "POST", ts.URL+"/sessions/"+state.ID+"/undo", nil, &state)
+	if event := next(t, spectator); event.Type != "state" || len(event.State.Moves) != 0 {
+		t.Fatalf("expected state after undo, got %+v", event)
+	}
+}
@@ -0,0 +1,487 @@
+// Package server exposes goblin game sessions over HTTP with JSON API
+//
+//	POST   /sessions               create a session
+//	GET    /sessions/{id}          get session state
+//	POST   /sessions/{id}/moves    make a human move, engine replies in the same request
+//	POST   /sessions/{id}/undo     take back the last human move and engine reply
+//	POST   /sessions/{id}/resign   resign the game
+//	DELETE /sessions/{id}          delete a session
+//	GET    /sessions/{id}/ws       WebSocket for live play and spectating, see live.go
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/risboo6909/goblin/misc"
+)
+
+// Server handles HTTP API requests, it is an http.Handler
+type Server struct {
+	Manager *misc.Manager
+
+	// time limit for engine moves
+	MoveTime time.Duration
+
+	// Engine makes a move for the engine side to move reporting search progress,
+	// the built-in engine is used if it is nil
+	Engine func(s *misc.Session, progress func(misc.SearchInfo)) error
+
+	// how often live clients are told about engine thinking time
+	ThinkingInterval time.Duration
+
+	upgrader websocket.Upgrader
+
+	roomsMu sync.Mutex
+	rooms   map[string]*room
+}
+
+// New creates a server keeping sessions in a given manager
+func New(manager *misc.Manager, moveTime time.Duration) *Server {
+	return &Server{Manager: manager, MoveTime: moveTime, ThinkingInterval: 500 * time.Millisecond,
+		rooms: make(map[string]*room)}
+}
+
+// CreateRequest describes a new session
+type CreateRequest struct {
+	BoardSide int    `json:"board_side"`
+	WinLength int    `json:"win_length"`
+	Rules     string `json:"rules"`
+
+	// side played by human: "X", "O" or "both", engine plays the other side
+	Human     string `json:"human"`
+	FirstMove string `json:"first_move"`
+
+	// engine search depth, the default one if zero
+	Depth int `json:"depth"`
+}
+
+// MoveRequest is a human move in standard notation like "H8"
+type MoveRequest struct {
+	Pos string `json:"pos"`
+}
+
+// ResignRequest names the side which resigns, the human side if empty
+type ResignRequest struct {
+	Player string `json:"player"`
+}
+
+// MoveState is a move in session history
+type MoveState struct {
+	Pos    string `json:"pos"`
+	Player string `json:"player"`
+}
+
+// SessionState is a session as returned by the API
+type SessionState struct {
+	ID        string `json:"id"`
+	BoardSide int    `json:"board_side"`
+	WinLength int    `json:"win_length"`
+	Rules     string `json:"rules"`
+	PlayerX   string `json:"player_x"`
+	PlayerO   string `json:"player_o"`
+
+	Turn   string `json:"turn"`
+	Status string `json:"status"`
+	Winner string `json:"winner,omitempty"`
+
+	Moves []MoveState `json:"moves"`
+
+	// board rows, "." is an empty cell
+	Board []string `json:"board"`
+
+	// cells of winning sequences
+	WinningCells []string `json:"winning_cells,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// httpError is an error with HTTP status code
+type httpError struct {
+	code int
+	msg  string
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &httpError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+func cellName(c misc.Cell) string {
+	if c == misc.E {
+		return ""
+	}
+	return string(c)
+}
+
+func parseSide(name string) (misc.Cell, error) {
+	switch strings.ToUpper(name) {
+	case "X":
+		return misc.X, nil
+	case "O":
+		return misc.O, nil
+	}
+	return misc.E, badRequest("unknown side %q", name)
+}
+
+// State converts a session into its API representation
+func State(s *misc.Session) SessionState {
+
+	notation := misc.DefaultNotation(s.Board.CellsVert)
+
+	state := SessionState{
+		ID:        s.SessionID,
+		BoardSide: s.Board.CellsHoriz,
+		WinLength: s.WinLength,
+		Rules:     s.Rules.String(),
+		PlayerX:   s.PlayerX.Kind.String(),
+		PlayerO:   s.PlayerO.Kind.String(),
+		Turn:      cellName(s.Turn),
+		Status:    s.Status.String(),
+		Winner:    cellName(s.Winner),
+		Moves:     make([]MoveState, len(s.History)),
+		Board:     make([]string, s.Board.CellsVert),
+	}
+
+	for i, move := range s.History {
+		state.Moves[i] = MoveState{notation.Format(move.Pos), cellName(move.Player)}
+	}
+
+	for row := 0; row < s.Board.CellsVert; row++ {
+		line := make([]byte, s.Board.CellsHoriz)
+		for col := range line {
+			line[col] = '.'
+			if cell := s.Board.GetCell(col, row); cell != misc.E {
+				line[col] = byte(cell)
+			}
+		}
+		state.Board[row] = string(line)
+	}
+
+	for _, interval := range s.Intervals {
+		for _, pos := range interval.Unfold() {
+			state.WinningCells = append(state.WinningCells, notation.Format(pos))
+		}
+	}
+
+	return state
+}
+
+func writeJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+
+	code := http.StatusInternalServerError
+
+	switch e := err.(type) {
+	case *httpError:
+		code = e.code
+	case misc.MoveError:
+		code = http.StatusConflict
+	default:
+		switch err {
+		case misc.ErrNoSession:
+			code = http.StatusNotFound
+		case misc.ErrTooManySessions:
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	writeJSON(w, code, errorResponse{err.Error()})
+}
+
+// decodeBody reads JSON request body, empty body leaves value untouched
+func decodeBody(r *http.Request, value interface{}) error {
+	if r.ContentLength == 0 {
+		return nil
+	}
+	if err := json.NewDecoder(r.Body).Decode(value); err != nil {
+		return badRequest("malformed request: %v", err)
+	}
+	return nil
+}
+
+// ServeHTTP routes API requests
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+
+	if parts[0] != "sessions" || len(parts) > 3 {
+		writeJSON(w, http.StatusNotFound, errorResponse{"not found"})
+		return
+	}
+
+	var err error
+
+	switch {
+
+	case len(parts) == 1 && r.Method == "POST":
+		err = srv.create(w, r)
+
+	case len(parts) == 2 && r.Method == "GET":
+		err = srv.get(w, parts[1])
+
+	case len(parts) == 2 && r.Method == "DELETE":
+		if err = srv.Manager.Remove(parts[1]); err == nil {
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+	case len(parts) == 3 && r.Method == "POST" && parts[2] == "moves":
+		err = srv.move(w, r, parts[1])
+
+	case len(parts) == 3 && r.Method == "POST" && parts[2] == "undo":
+		err = srv.undo(w, parts[1])
+
+	case len(parts) == 3 && r.Method == "POST" && parts[2] == "resign":
+		err = srv.resign(w, r, parts[1])
+
+	case len(parts) == 3 && r.Method == "GET" && parts[2] == "ws":
+		err = srv.live(w, r, parts[1])
+
+	default:
+		err = &httpError{http.StatusMethodNotAllowed, "method not allowed"}
+	}
+
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+// sessionOptions validates create request and converts it into session options
+func (srv *Server) sessionOptions(req CreateRequest) (misc.SessionOptions, error) {
+
+	options := misc.SessionOptions{BoardSide: 15, WinLength: 5, Rules: misc.Freestyle, FirstMove: misc.X}
+
+	if req.BoardSide != 0 {
+		options.BoardSide = req.BoardSide
+	}
+	if req.WinLength != 0 {
+		options.WinLength = req.WinLength
+	}
+
+	if options.BoardSide < 3 || options.BoardSide > misc.MaxBoardSide {
+		return options, badRequest("invalid board side %d", options.BoardSide)
+	}
+	if options.WinLength < 3 || options.WinLength > options.BoardSide {
+		return options, badRequest("invalid win length %d", options.WinLength)
+	}
+
+	var err error
+
+	if req.Rules != "" {
+		if options.Rules, err = misc.ParseRules(req.Rules); err != nil {
+			return options, badRequest("%v", err)
+		}
+	}
+
+	if req.FirstMove != "" {
+		if options.FirstMove, err = parseSide(req.FirstMove); err != nil {
+			return options, err
+		}
+	}
+
+	engine := misc.EnginePlayer("Goblin", req.Depth)
+	engine.AI.TimeLimit = srv.MoveTime
+
+	options.PlayerX, options.PlayerO = misc.HumanPlayer("Human"), engine
+
+	switch strings.ToUpper(req.Human) {
+	case "", "X":
+	case "O":
+		options.PlayerX, options.PlayerO = engine, misc.HumanPlayer("Human")
+	case "BOTH":
+		options.PlayerO = misc.HumanPlayer("Human")
+	default:
+		return options, badRequest("unknown human side %q", req.Human)
+	}
+
+	return options, nil
+}
+
+// builtinEngine makes a move with session's own engine settings
+func builtinEngine(s *misc.Session, progress func(misc.SearchInfo)) error {
+	return s.MakeMoveProgress(progress)
+}
+
+// apply runs fn changing a session and tells live clients about the changes
+func (srv *Server) apply(s *misc.Session, fn func() error) error {
+
+	history, wasOver := len(s.History), s.Over()
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	if r := srv.findRoom(s.SessionID); r != nil {
+		r.changed(s, history, wasOver)
+	}
+
+	return nil
+}
+
+// think lets engine move while it is its turn
+func (srv *Server) think(s *misc.Session) error {
+
+	engine := srv.Engine
+	if engine == nil {
+		engine = builtinEngine
+	}
+
+	for s.EngineToMove() {
+		err := srv.apply(s, func() error {
+			stop := srv.thinking(s)
+			defer stop()
+			return engine(s, func(info misc.SearchInfo) {
+				if r := srv.findRoom(s.SessionID); r != nil {
+					r.broadcast(thinkingEvent(s, info))
+				}
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (srv *Server) create(w http.ResponseWriter, r *http.Request) error {
+
+	var req CreateRequest
+
+	if err := decodeBody(r, &req); err != nil {
+		return err
+	}
+
+	options, err := srv.sessionOptions(req)
+	if err != nil {
+		return err
+	}
+
+	id, err := srv.Manager.Create(options)
+	if err != nil {
+		return err
+	}
+
+	return srv.Manager.With(id, func(s *misc.Session) error {
+		// engine opens the game if it moves first
+		if err := srv.think(s); err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusCreated, State(s))
+		return nil
+	})
+}
+
+func (srv *Server) get(w http.ResponseWriter, id string) error {
+	return srv.Manager.With(id, func(s *misc.Session) error {
+		writeJSON(w, http.StatusOK, State(s))
+		return nil
+	})
+}
+
+func (srv *Server) move(w http.ResponseWriter, r *http.Request, id string) error {
+
+	var req MoveRequest
+
+	if err := decodeBody(r, &req); err != nil {
+		return err
+	}
+
+	return srv.Manager.With(id, func(s *misc.Session) error {
+
+		pos, err := misc.DefaultNotation(s.Board.CellsVert).Parse(req.Pos)
+		if err != nil {
+			return badRequest("%v", err)
+		}
+
+		if err := srv.apply(s, func() error { return s.Play(pos) }); err != nil {
+			return err
+		}
+
+		if err := srv.think(s); err != nil {
+			return err
+		}
+
+		writeJSON(w, http.StatusOK, State(s))
+		return nil
+	})
+}
+
+// undo takes back the last move and engine replies up to the last human move
+func undo(s *misc.Session) error {
+
+	if err := s.Undo(); err != nil {
+		return err
+	}
+
+	for len(s.History) != 0 && s.Player(s.Turn).Kind == misc.Engine {
+		s.Undo()
+	}
+
+	return nil
+}
+
+func (srv *Server) undo(w http.ResponseWriter, id string) error {
+
+	return srv.Manager.With(id, func(s *misc.Session) error {
+
+		if err := srv.apply(s, func() error { return undo(s) }); err != nil {
+			return err
+		}
+
+		if err := srv.think(s); err != nil {
+			return err
+		}
+
+		writeJSON(w, http.StatusOK, State(s))
+		return nil
+	})
+}
+
+func (srv *Server) resign(w http.ResponseWriter, r *http.Request, id string) error {
+
+	var req ResignRequest
+
+	if err := decodeBody(r, &req); err != nil {
+		return err
+	}
+
+	return srv.Manager.With(id, func(s *misc.Session) error {
+
+		player := s.Turn
+		if req.Player != "" {
+			var err error
+			if player, err = parseSide(req.Player); err != nil {
+				return err
+			}
+		} else if s.PlayerX.Kind != s.PlayerO.Kind {
+			player = misc.X
+			if s.PlayerO.Kind == misc.Human {
+				player = misc.O
+			}
+		}
+
+		if err := srv.apply(s, func() error { return s.Resign(player) }); err != nil {
+			return err
+		}
+
+		writeJSON(w, http.StatusOK, State(s))
+		return nil
+	})
+}
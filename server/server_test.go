@@ -0,0 +1,167 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/risboo6909/goblin/misc"
+)
+
+func newTestServer() *httptest.Server {
+	return httptest.NewServer(New(misc.NewManager(time.Hour), 200*time.Millisecond))
+}
+
+// call makes API request and decodes response into result if it is not nil
+func call(t *testing.T, method, url string, body interface{}, result interface{}) int {
+
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			t.Fatalf("%s %s: %v", method, url, err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+func TestHumanVsEngine(t *testing.T) {
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	var state SessionState
+
+	code := call(t, "POST", ts.URL+"/sessions", CreateRequest{BoardSide: 7, WinLength: 4, Depth: 1}, &state)
+	if code != http.StatusCreated || state.ID == "" {
+		t.Fatalf("create: got %d %+v", code, state)
+	}
+	if state.Turn != "X" || state.Status != "in progress" || len(state.Board) != 7 {
+		t.Fatalf("create: unexpected state %+v", state)
+	}
+
+	url := ts.URL + "/sessions/" + state.ID
+
+	code = call(t, "POST", url+"/moves", MoveRequest{"D4"}, &state)
+	if code != http.StatusOK {
+		t.Fatalf("move: got %d", code)
+	}
+	// engine replies in the same request
+	if len(state.Moves) != 2 || state.Moves[0] != (MoveState{"D4", "X"}) || state.Moves[1].Player != "O" {
+		t.Fatalf("move: unexpected moves %+v", state.Moves)
+	}
+	if state.Board[3][3] != 'X' {
+		t.Fatalf("move: unexpected board %q", state.Board)
+	}
+
+	var errResp errorResponse
+
+	if code = call(t, "POST", url+"/moves", MoveRequest{"D4"}, &errResp); code != http.StatusConflict {
+		t.Fatalf("occupied cell: got %d %+v", code, errResp)
+	}
+	if code = call(t, "POST", url+"/moves", MoveRequest{"Z99"}, &errResp); code != http.StatusConflict {
+		t.Fatalf("out of the board: got %d %+v", code, errResp)
+	}
+	if code = call(t, "POST", url+"/moves", MoveRequest{"4D"}, &errResp); code != http.StatusBadRequest {
+		t.Fatalf("malformed position: got %d %+v", code, errResp)
+	}
+
+	if code = call(t, "POST", url+"/undo", nil, &state); code != http.StatusOK || len(state.Moves) != 0 {
+		t.Fatalf("undo: got %d %+v", code, state.Moves)
+	}
+
+	if code = call(t, "POST", url+"/resign", nil, &state); code != http.StatusOK {
+		t.Fatalf("resign: got %d", code)
+	}
+	if state.Status != "resigned" || state.Winner != "O" {
+		t.Fatalf("resign: unexpected state %+v", state)
+	}
+
+	if code = call(t, "GET", url, nil, &state); code != http.StatusOK || state.Status != "resigned" {
+		t.Fatalf("get: got %d %+v", code, state)
+	}
+
+	if code = call(t, "DELETE", url, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("delete: got %d", code)
+	}
+	if code = call(t, "GET", url, nil, &errResp); code != http.StatusNotFound {
+		t.Fatalf("get deleted: got %d", code)
+	}
+}
+
+func TestEngineMovesFirst(t *testing.T) {
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	var state SessionState
+
+	code := call(t, "POST", ts.URL+"/sessions", CreateRequest{BoardSide: 7, WinLength: 4, Human: "O", Depth: 1}, &state)
+	if code != http.StatusCreated {
+		t.Fatalf("create: got %d", code)
+	}
+	if len(state.Moves) != 1 || state.Moves[0].Player != "X" || state.Turn != "O" {
+		t.Fatalf("create: unexpected state %+v", state)
+	}
+}
+
+func TestHumanVsHumanWin(t *testing.T) {
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	var state SessionState
+
+	call(t, "POST", ts.URL+"/sessions", CreateRequest{BoardSide: 7, WinLength: 3, Human: "both"}, &state)
+
+	url := ts.URL + "/sessions/" + state.ID
+
+	for _, pos := range []string{"A1", "A2", "B1", "B2", "C1"} {
+		if code := call(t, "POST", url+"/moves", MoveRequest{pos}, &state); code != http.StatusOK {
+			t.Fatalf("move %s: got %d", pos, code)
+		}
+	}
+
+	if state.Status != "won" || state.Winner != "X" || len(state.WinningCells) != 3 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+
+	ts := newTestServer()
+	defer ts.Close()
+
+	var errResp errorResponse
+
+	for _, req := range []CreateRequest{{BoardSide: 2}, {BoardSide: 26}, {WinLength: 20}, {Rules: "renju"}, {Human: "Z"}} {
+		if code := call(t, "POST", ts.URL+"/sessions", req, &errResp); code != http.StatusBadRequest {
+			t.Errorf("create %+v: got %d", req, code)
+		}
+	}
+
+	if code := call(t, "GET", ts.URL+"/games", nil, &errResp); code != http.StatusNotFound {
+		t.Errorf("unknown path: got %d", code)
+	}
+	if code := call(t, "PUT", ts.URL+"/sessions", nil, &errResp); code != http.StatusMethodNotAllowed {
+		t.Errorf("unknown method: got %d", code)
+	}
+}
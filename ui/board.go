@@ -2,6 +2,7 @@ package ui
 
 import (
 	"math"
+	"strconv"
 
 	"github.com/nsf/termbox-go"
 	"github.com/risboo6909/goblin/misc"
@@ -12,6 +13,10 @@ import (
 type BoardAttrs struct {
 	BoardColor, BoardBg   termbox.Attribute
 	LabelsColor, LabelsBg termbox.Attribute
+
+	// colors of stones and of winning lines
+	XColor, OColor  termbox.Attribute
+	WinColor, WinBg termbox.Attribute
 }
 
 type DrawableBoard struct {
@@ -21,7 +26,16 @@ type DrawableBoard struct {
 	// upper-left corner position of a board
 	X, Y int
 
+	// notation used for column and row labels
+	Notation misc.Notation
+
 	BoardAttrs
+
+	// moves made on the board, the last move of each side is highlighted
+	History []misc.Move
+
+	// stones are shown as numbers of their moves if ShowNumbers is true
+	ShowNumbers bool
 }
 
 func NewBoard(cellsHoriz, cellsVert, x, y int, boardColor, boardBg, labelsColor,
@@ -32,7 +46,9 @@ func NewBoard(cellsHoriz, cellsVert, x, y int, boardColor, boardBg, labelsColor,
 
 func CloneExistingBoard(board *misc.BoardDescription, x, y int, boardColor, boardBg, labelsColor,
 	labelsBg termbox.Attribute) *DrawableBoard {
-	return &DrawableBoard{board, x, y, BoardAttrs{boardColor, boardBg, labelsColor, labelsBg}}
+	return &DrawableBoard{BoardDescription: board, X: x, Y: y, Notation: misc.DefaultNotation(board.CellsVert),
+		BoardAttrs: BoardAttrs{boardColor, boardBg, labelsColor, labelsBg,
+			termbox.ColorYellow, termbox.ColorGreen, termbox.ColorWhite, termbox.ColorBlack}}
 }
 
 func modN(n float64) func(int) float64 {
@@ -52,6 +68,26 @@ func getScrY(board *DrawableBoard, row int) int {
 	return board.Y + 1 + row*2
 }
 
+// ScreenToCell returns the cell drawn at screen position x, y, the stone
+// character and a character on each side of it belong to the cell,
+// ok is false if there is no cell at x, y
+func ScreenToCell(board *DrawableBoard, x, y int) (col, row int, ok bool) {
+
+	dx, dy := x-getScrX(board, 0), y-getScrY(board, 0)
+
+	if dx < -1 || dy < 0 || dy%2 != 0 {
+		return 0, 0, false
+	}
+
+	col, row = (dx+1)/4, dy/2
+
+	if dx-col*4 > 1 || col >= board.CellsHoriz || row >= board.CellsVert {
+		return 0, 0, false
+	}
+
+	return col, row, true
+}
+
 // drawHorizLine draws horizontal board lines
 func drawHorizLine(color, bgcolor termbox.Attribute, x, y, width int) {
 
@@ -77,18 +113,14 @@ func drawTopAndBottom(x, y int, p *DrawableBoard, left, middle, right rune, labe
 
 	if labels {
 
-		var letter = 'A'
-
 		printTb(x-2, y-1, p.LabelsColor, p.LabelsBg, "  ")
 		for i := 0; i <= p.GetWidth(); i++ {
+			termbox.SetCell(x+i, y-1, ' ', p.LabelsColor, p.LabelsBg)
+		}
 
-			if mod4(i) == 0 {
-				termbox.SetCell(x+i, y-1, letter, p.LabelsColor, p.LabelsBg)
-				letter++
-			} else {
-				termbox.SetCell(x+i, y-1, ' ', p.LabelsColor, p.LabelsBg)
-			}
-
+		// column names may be longer than one letter on big boards
+		for col := 0; col < p.CellsHoriz; col++ {
+			printTb(x+col*4, y-1, p.LabelsColor, p.LabelsBg, p.Notation.ColumnName(col))
 		}
 	}
 
@@ -120,13 +152,13 @@ func drawLeftAndRight(x, y int, p *DrawableBoard, middle rune, labels bool) {
 
 	if labels {
 
-		var index = 1
+		var row = 0
 
 		for i := 0; i <= p.GetHeight(); i++ {
 
 			if mod2(i) == 0 {
-				printfTb(x-2, y+i, p.LabelsColor, p.LabelsBg, "%2d", index)
-				index++
+				printfTb(x-2, y+i, p.LabelsColor, p.LabelsBg, "%2s", p.Notation.RowName(row))
+				row++
 			} else {
 				printTb(x-2, y+i, p.LabelsColor, p.LabelsBg, "  ")
 			}
@@ -151,19 +183,54 @@ func drawLeftAndRight(x, y int, p *DrawableBoard, middle rune, labels bool) {
 }
 
 func fillBoard(board *DrawableBoard) {
+
+	// move numbers and the last move of each side
+	numbers := make(map[misc.CellPosition]int)
+	last := make(map[misc.Cell]misc.CellPosition)
+
+	for i, move := range board.History {
+		numbers[move.Pos] = i + 1
+		last[move.Player] = move.Pos
+	}
+
 	for i := 0; i < board.CellsHoriz; i++ {
 		for j := 0; j < board.CellsVert; j++ {
 
 			scrX := getScrX(board, i)
 			scrY := getScrY(board, j)
 
-			if board.GetCell(i, j) == misc.X {
-				termbox.SetCell(scrX, scrY, misc.X, termbox.ColorYellow|termbox.AttrBold, board.BoardBg)
+			cell := board.GetCell(i, j)
 
-			} else if board.GetCell(i, j) == misc.O {
-				termbox.SetCell(scrX, scrY, misc.O, termbox.ColorGreen|termbox.AttrBold, board.BoardBg)
+			var color termbox.Attribute
+
+			if cell == misc.X {
+				color = board.XColor
+			} else if cell == misc.O {
+				color = board.OColor
+			} else {
+				continue
 			}
 
+			pos := misc.CellPosition{Col: i, Row: j}
+
+			fg, bg := color|termbox.AttrBold, board.BoardBg
+
+			// the last moves are drawn with swapped colors
+			if lastPos, found := last[cell]; found && lastPos == pos {
+				fg, bg = Plain(board.BoardBg), Plain(color)
+				// colors which can't be swapped are reversed instead
+				if fg == bg {
+					fg, bg = color|termbox.AttrBold|termbox.AttrReverse, board.BoardBg
+				}
+			}
+
+			if n := numbers[pos]; board.ShowNumbers && n > 0 {
+				// numbers are centered at the intersection
+				text := strconv.Itoa(n)
+				printTb(scrX-(len(text)-1)/2, scrY, fg, bg, text)
+			} else {
+				termbox.SetCell(scrX, scrY, rune(cell), fg, bg)
+			}
 		}
 	}
 }
@@ -191,9 +258,8 @@ func drawInterval() func(*DrawableBoard, []misc.Interval) {
 			for _, interval := range intervals {
 				for _, coord := range interval.Unfold() {
 					scrX, scrY := getScrX(board, coord.Col), getScrY(board, coord.Row)
-					// TODO: Make colors customizable
 					termbox.SetCell(scrX, scrY, rune(board.GetCell(coord.Col, coord.Row)),
-						termbox.ColorWhite, termbox.ColorBlack)
+						board.WinColor, board.WinBg)
 				}
 			}
 
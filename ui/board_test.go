@@ -0,0 +1,44 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestScreenToCell(t *testing.T) {
+
+	for _, offset := range []struct{ x, y int }{{0, 0}, {5, 3}, {-7, -2}} {
+
+		board := NewBoard(9, 7, offset.x, offset.y, termbox.ColorBlack, termbox.ColorBlue,
+			termbox.ColorRed, termbox.ColorBlack)
+
+		// every cell is found at its own screen position and the characters around it
+		for col := 0; col < board.CellsHoriz; col++ {
+			for row := 0; row < board.CellsVert; row++ {
+				for dx := -1; dx <= 1; dx++ {
+					c, r, ok := ScreenToCell(board, getScrX(board, col)+dx, getScrY(board, row))
+					if !ok || c != col || r != row {
+						t.Fatalf("offset %v: cell %d, %d, dx %d: got %d, %d, %v", offset, col, row, dx, c, r, ok)
+					}
+				}
+			}
+		}
+
+		x, y := getScrX(board, 0), getScrY(board, 0)
+
+		for _, miss := range []struct{ x, y int }{
+			{x - 2, y},           // left of the board
+			{x, y - 1},           // above the board
+			{x, y + 1},           // between rows
+			{x + 2, y},           // between columns
+			{x + 9*4, y},         // right of the board
+			{x, y + 7*2},         // under the board
+			{x + 9*4 - 1, y + 2}, // right next to the last column
+		} {
+			if c, r, ok := ScreenToCell(board, miss.x, miss.y); ok {
+				t.Errorf("offset %v: unexpected cell %d, %d at %d, %d", offset, c, r, miss.x, miss.y)
+			}
+		}
+	}
+}
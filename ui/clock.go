@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// FormatClock formats time left as minutes and seconds, tenths of a second
+// are shown for the last ten seconds
+func FormatClock(left time.Duration) string {
+
+	if left < 10*time.Second {
+		tenths := int(left / (100 * time.Millisecond))
+		return fmt.Sprintf("0:%02d.%d", tenths/10, tenths%10)
+	}
+
+	seconds := int(left / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// DrawClock draws player's clock at x, y, running clock is drawn with swapped
+// colors, returns the width of drawn text
+func DrawClock(x, y int, fg, bg termbox.Attribute, name string, left time.Duration, running bool) int {
+	if running {
+		fg, bg = bg, fg
+	}
+	msg := fmt.Sprintf(" %s %s ", name, FormatClock(left))
+	printTb(x, y, fg, bg, msg)
+	return len([]rune(msg))
+}
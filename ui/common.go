@@ -17,3 +17,8 @@ func printfTb(x, y int, fg, bg termbox.Attribute, format string, args ...interfa
 	s := fmt.Sprintf(format, args...)
 	printTb(x, y, fg, bg, s)
 }
+
+// DrawText prints a message starting at x, y
+func DrawText(x, y int, fg, bg termbox.Attribute, msg string) {
+	printTb(x, y, fg, bg, msg)
+}
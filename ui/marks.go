@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"github.com/nsf/termbox-go"
+	"github.com/risboo6909/goblin/misc"
+)
+
+// Mark highlights a board cell, the character already drawn in the cell is
+// kept if Ch is zero
+type Mark struct {
+	Pos              misc.CellPosition
+	Ch               rune
+	FgColor, BgColor termbox.Attribute
+}
+
+// DrawMarks draws marks over a drawn board, marks of cells out of the board are skipped
+func DrawMarks(board *DrawableBoard, marks []Mark) {
+
+	width, height := termbox.Size()
+	buffer := termbox.CellBuffer()
+
+	for _, mark := range marks {
+
+		if _, err := board.ToLinear(mark.Pos.Col, mark.Pos.Row); err != nil {
+			continue
+		}
+
+		x, y := getScrX(board, mark.Pos.Col), getScrY(board, mark.Pos.Row)
+
+		ch := mark.Ch
+		if ch == 0 && x >= 0 && x < width && y >= 0 && y < height {
+			ch = buffer[y*width+x].Ch
+		}
+
+		termbox.SetCell(x, y, ch, mark.FgColor, mark.BgColor)
+	}
+}
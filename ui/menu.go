@@ -0,0 +1,208 @@
+package ui
+
+import "github.com/nsf/termbox-go"
+
+// MenuItem is a menu entry, an entry with values is an option whose value
+// is changed by left and right arrows
+type MenuItem struct {
+	Title  string
+	Values []string
+
+	// index of the chosen value
+	Selected int
+}
+
+// Value returns chosen value of an option, empty string for plain entries
+func (item *MenuItem) Value() string {
+	if len(item.Values) == 0 {
+		return ""
+	}
+	return item.Values[item.Selected]
+}
+
+// Select chooses a given value of an option, returns false if there is no such value
+func (item *MenuItem) Select(value string) bool {
+	for i, v := range item.Values {
+		if v == value {
+			item.Selected = i
+			return true
+		}
+	}
+	return false
+}
+
+// Menu is a vertical list of entries with one of them highlighted
+type Menu struct {
+	Title string
+	Items []MenuItem
+
+	// index of the highlighted entry
+	Current int
+
+	FgColor, BgColor     termbox.Attribute
+	CurrentFg, CurrentBg termbox.Attribute
+}
+
+// NewMenu creates a menu of plain entries with given titles
+func NewMenu(title string, fgColor, bgColor, currentFg, currentBg termbox.Attribute, items ...string) *Menu {
+	menu := &Menu{Title: title, FgColor: fgColor, BgColor: bgColor, CurrentFg: currentFg, CurrentBg: currentBg}
+	for _, item := range items {
+		menu.AddItem(item)
+	}
+	return menu
+}
+
+// AddItem appends a plain entry
+func (m *Menu) AddItem(title string) {
+	m.Items = append(m.Items, MenuItem{Title: title})
+}
+
+// AddOption appends an option with given values
+func (m *Menu) AddOption(title string, values ...string) {
+	m.Items = append(m.Items, MenuItem{Title: title, Values: values})
+}
+
+// Item returns an entry with a given title, nil if there is no such entry
+func (m *Menu) Item(title string) *MenuItem {
+	for i := range m.Items {
+		if m.Items[i].Title == title {
+			return &m.Items[i]
+		}
+	}
+	return nil
+}
+
+// Chosen returns title of the highlighted entry
+func (m *Menu) Chosen() string {
+	return m.Items[m.Current].Title
+}
+
+// HandleKey moves highlight and changes options, returns done = true when
+// a plain entry is chosen by Enter or Esc is pressed, cancelled is true for Esc
+func (m *Menu) HandleKey(ev termbox.Event) (done, cancelled bool) {
+
+	item := &m.Items[m.Current]
+
+	switch ev.Key {
+
+	case termbox.KeyArrowUp:
+		m.Current = (m.Current + len(m.Items) - 1) % len(m.Items)
+
+	case termbox.KeyArrowDown, termbox.KeyTab:
+		m.Current = (m.Current + 1) % len(m.Items)
+
+	case termbox.KeyArrowLeft:
+		if len(item.Values) > 0 {
+			item.Selected = (item.Selected + len(item.Values) - 1) % len(item.Values)
+		}
+
+	case termbox.KeyArrowRight, termbox.KeySpace:
+		if len(item.Values) > 0 {
+			item.Selected = (item.Selected + 1) % len(item.Values)
+		}
+
+	case termbox.KeyEnter:
+		if len(item.Values) == 0 {
+			return true, false
+		}
+		item.Selected = (item.Selected + 1) % len(item.Values)
+
+	case termbox.KeyEsc:
+		return true, true
+	}
+
+	return false, false
+}
+
+// itemLines returns menu entries as they are drawn, option values are aligned
+// to the longest entry title
+func (m *Menu) itemLines() []string {
+
+	width := 0
+	for _, item := range m.Items {
+		if l := len([]rune(item.Title)); l > width {
+			width = l
+		}
+	}
+
+	lines := make([]string, len(m.Items))
+
+	for i, item := range m.Items {
+		lines[i] = " " + item.Title + " "
+		if len(item.Values) > 0 {
+			lines[i] = " " + padRight(item.Title, width) + "  < " + item.Value() + " > "
+		}
+	}
+
+	return lines
+}
+
+// drawItems draws menu entries starting at x, y, the highlighted entry is
+// padded up to width runes
+func drawItems(x, y, width int, m *Menu) {
+	for i, line := range m.itemLines() {
+		fg, bg := m.FgColor, m.BgColor
+		if i == m.Current {
+			fg, bg = m.CurrentFg, m.CurrentBg
+			line = padRight(line, width)
+		}
+		printTb(x, y+i, fg, bg, line)
+	}
+}
+
+// DrawMenu draws menu title and entries at x, y
+func DrawMenu(x, y int, m *Menu) {
+	printTb(x, y, m.FgColor|termbox.AttrBold, m.BgColor, m.Title)
+	drawItems(x, y+2, 0, m)
+}
+
+// DrawDialog draws a framed menu at x, y with text lines between its title and entries
+func DrawDialog(x, y int, m *Menu, text []string) {
+
+	width := len([]rune(m.Title)) + 2
+	for _, line := range append(text, m.itemLines()...) {
+		if l := len([]rune(line)) + 2; l > width {
+			width = l
+		}
+	}
+
+	height := len(text) + len(m.Items) + 4
+
+	// frame with cleared inside
+	for row := 0; row <= height+1; row++ {
+		for col := 0; col <= width+1; col++ {
+			ch := ' '
+			switch {
+			case row == 0 && col == 0:
+				ch = '┌'
+			case row == 0 && col == width+1:
+				ch = '┐'
+			case row == height+1 && col == 0:
+				ch = '└'
+			case row == height+1 && col == width+1:
+				ch = '┘'
+			case row == 0 || row == height+1:
+				ch = '─'
+			case col == 0 || col == width+1:
+				ch = '│'
+			}
+			termbox.SetCell(x+col, y+row, ch, m.FgColor, m.BgColor)
+		}
+	}
+
+	printTb(x+2, y+1, m.FgColor|termbox.AttrBold, m.BgColor, m.Title)
+
+	for i, line := range text {
+		printTb(x+2, y+3+i, m.FgColor, m.BgColor, line)
+	}
+
+	drawItems(x+1, y+4+len(text), width, m)
+}
+
+// padRight pads s with spaces up to width runes
+func padRight(s string, width int) string {
+	for n := len([]rune(s)); n < width; n++ {
+		s += " "
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// PanelLine is a line of a panel with its own colors
+type PanelLine struct {
+	Text             string
+	FgColor, BgColor termbox.Attribute
+}
+
+// DrawPanel draws a framed panel of a given size at x, y with a title on its
+// top border, lines which don't fit are cut off
+func DrawPanel(x, y, width, height int, title string, fg, bg termbox.Attribute, lines []PanelLine) {
+
+	if width < 4 || height < 3 {
+		return
+	}
+
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			ch := ' '
+			switch {
+			case row == 0 && col == 0:
+				ch = '┌'
+			case row == 0 && col == width-1:
+				ch = '┐'
+			case row == height-1 && col == 0:
+				ch = '└'
+			case row == height-1 && col == width-1:
+				ch = '┘'
+			case row == 0 || row == height-1:
+				ch = '─'
+			case col == 0 || col == width-1:
+				ch = '│'
+			}
+			termbox.SetCell(x+col, y+row, ch, fg, bg)
+		}
+	}
+
+	if title != "" {
+		printTb(x+2, y, fg|termbox.AttrBold, bg, cut(" "+title+" ", width-4))
+	}
+
+	for i, line := range lines {
+		if i >= height-2 {
+			break
+		}
+		printTb(x+2, y+1+i, line.FgColor, line.BgColor, cut(line.Text, width-4))
+	}
+}
+
+// DrawStatusBar fills a line of a given width at x, y and prints left text at
+// its start and right text at its end, left text is cut if they don't fit
+func DrawStatusBar(x, y, width int, fg, bg termbox.Attribute, left, right string) {
+
+	printTb(x, y, fg, bg, strings.Repeat(" ", width))
+
+	rightLen := len([]rune(right))
+	if rightLen > width {
+		right, rightLen = cut(right, width), width
+	}
+
+	printTb(x, y, fg, bg, cut(left, width-rightLen-1))
+	printTb(x+width-rightLen, y, fg|termbox.AttrBold, bg, right)
+}
+
+// cut shortens s to at most width runes
+func cut(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if r := []rune(s); len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
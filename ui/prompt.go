@@ -0,0 +1,57 @@
+package ui
+
+import "github.com/nsf/termbox-go"
+
+// Prompt is a single line text input
+type Prompt struct {
+	Title string
+	Text  []rune
+
+	FgColor, BgColor termbox.Attribute
+}
+
+// NewPrompt creates a prompt with a given title and initial text
+func NewPrompt(title, text string, fgColor, bgColor termbox.Attribute) *Prompt {
+	return &Prompt{title, []rune(text), fgColor, bgColor}
+}
+
+// HandleKey edits prompt text, returns done = true when Enter or Esc is pressed,
+// cancelled is true for Esc
+func (p *Prompt) HandleKey(ev termbox.Event) (done, cancelled bool) {
+
+	switch ev.Key {
+
+	case termbox.KeyEnter:
+		return true, false
+
+	case termbox.KeyEsc:
+		return true, true
+
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(p.Text) > 0 {
+			p.Text = p.Text[:len(p.Text)-1]
+		}
+
+	case termbox.KeySpace:
+		p.Text = append(p.Text, ' ')
+
+	default:
+		if ev.Ch != 0 {
+			p.Text = append(p.Text, ev.Ch)
+		}
+	}
+
+	return false, false
+}
+
+// Value returns entered text
+func (p *Prompt) Value() string {
+	return string(p.Text)
+}
+
+// DrawPrompt draws prompt title and text at x, y and puts terminal cursor after the text
+func DrawPrompt(x, y int, p *Prompt) {
+	msg := p.Title + ": " + string(p.Text)
+	printTb(x, y, p.FgColor, p.BgColor, msg)
+	termbox.SetCursor(x+len([]rune(msg)), y)
+}
@@ -0,0 +1,270 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Theme holds colors of all screen elements
+type Theme struct {
+	Name string
+
+	// board lines, labels, stones and winning lines
+	Board BoardAttrs
+
+	CursorFg, CursorBg termbox.Attribute
+
+	// screen background, messages, menus and panels
+	TextFg, TextBg termbox.Attribute
+
+	// engine status line
+	AccentFg termbox.Attribute
+
+	// highlighted menu entries and the status bar
+	SelectedFg, SelectedBg termbox.Attribute
+
+	// the move suggested by a hint and other candidates
+	HintFg, HintBg           termbox.Attribute
+	CandidateFg, CandidateBg termbox.Attribute
+
+	// heatmap colors from the lowest to the highest score
+	Heatmap []termbox.Attribute
+}
+
+// Themes are built-in themes, the first one is the default
+var Themes = []Theme{
+	{
+		Name: "dark",
+		Board: BoardAttrs{BoardColor: termbox.ColorBlack, BoardBg: termbox.ColorBlue,
+			LabelsColor: termbox.ColorRed, LabelsBg: termbox.ColorBlack,
+			XColor: termbox.ColorYellow, OColor: termbox.ColorGreen,
+			WinColor: termbox.ColorWhite, WinBg: termbox.ColorBlack},
+		CursorFg: termbox.ColorGreen, CursorBg: termbox.ColorWhite,
+		TextFg: termbox.ColorWhite, TextBg: termbox.ColorBlack,
+		AccentFg:   termbox.ColorYellow,
+		SelectedFg: termbox.ColorBlack, SelectedBg: termbox.ColorWhite,
+		HintFg: termbox.ColorWhite | termbox.AttrBold, HintBg: termbox.ColorMagenta,
+		CandidateFg: termbox.ColorBlack, CandidateBg: termbox.ColorCyan,
+		Heatmap: []termbox.Attribute{termbox.ColorBlue, termbox.ColorCyan, termbox.ColorGreen,
+			termbox.ColorYellow, termbox.ColorRed},
+	},
+	{
+		Name: "light",
+		Board: BoardAttrs{BoardColor: termbox.ColorBlack, BoardBg: termbox.ColorWhite,
+			LabelsColor: termbox.ColorBlue, LabelsBg: termbox.ColorWhite,
+			XColor: termbox.ColorRed, OColor: termbox.ColorBlue,
+			WinColor: termbox.ColorWhite, WinBg: termbox.ColorRed},
+		CursorFg: termbox.ColorWhite, CursorBg: termbox.ColorGreen,
+		TextFg: termbox.ColorBlack, TextBg: termbox.ColorWhite,
+		AccentFg:   termbox.ColorBlue,
+		SelectedFg: termbox.ColorWhite, SelectedBg: termbox.ColorBlue,
+		HintFg: termbox.ColorWhite | termbox.AttrBold, HintBg: termbox.ColorMagenta,
+		CandidateFg: termbox.ColorBlack, CandidateBg: termbox.ColorCyan,
+		Heatmap: []termbox.Attribute{termbox.ColorCyan, termbox.ColorGreen, termbox.ColorYellow,
+			termbox.ColorMagenta, termbox.ColorRed},
+	},
+	{
+		Name: "high-contrast",
+		Board: BoardAttrs{BoardColor: termbox.ColorWhite, BoardBg: termbox.ColorBlack,
+			LabelsColor: termbox.ColorWhite | termbox.AttrBold, LabelsBg: termbox.ColorBlack,
+			XColor: termbox.ColorYellow, OColor: termbox.ColorCyan,
+			WinColor: termbox.ColorBlack, WinBg: termbox.ColorWhite},
+		CursorFg: termbox.ColorBlack, CursorBg: termbox.ColorYellow,
+		TextFg: termbox.ColorWhite | termbox.AttrBold, TextBg: termbox.ColorBlack,
+		AccentFg:   termbox.ColorYellow | termbox.AttrBold,
+		SelectedFg: termbox.ColorBlack, SelectedBg: termbox.ColorYellow,
+		HintFg: termbox.ColorBlack, HintBg: termbox.ColorWhite,
+		CandidateFg: termbox.ColorBlack, CandidateBg: termbox.ColorCyan,
+		Heatmap: []termbox.Attribute{termbox.ColorBlue, termbox.ColorGreen, termbox.ColorYellow,
+			termbox.ColorRed, termbox.ColorMagenta},
+	},
+	{
+		Name: "monochrome",
+		Board: BoardAttrs{BoardColor: termbox.ColorDefault, BoardBg: termbox.ColorDefault,
+			LabelsColor: termbox.ColorDefault, LabelsBg: termbox.ColorDefault,
+			XColor: termbox.ColorDefault, OColor: termbox.ColorDefault,
+			WinColor: termbox.ColorDefault | termbox.AttrReverse, WinBg: termbox.ColorDefault},
+		CursorFg: termbox.ColorDefault | termbox.AttrReverse, CursorBg: termbox.ColorDefault,
+		TextFg: termbox.ColorDefault, TextBg: termbox.ColorDefault,
+		AccentFg:   termbox.ColorDefault | termbox.AttrBold,
+		SelectedFg: termbox.ColorDefault | termbox.AttrReverse, SelectedBg: termbox.ColorDefault,
+		HintFg: termbox.ColorDefault | termbox.AttrReverse | termbox.AttrBold, HintBg: termbox.ColorDefault,
+		CandidateFg: termbox.ColorDefault | termbox.AttrUnderline, CandidateBg: termbox.ColorDefault,
+		Heatmap: []termbox.Attribute{termbox.ColorDefault, termbox.ColorDefault | termbox.AttrReverse},
+	},
+}
+
+// attributes are bits above 256-color palette
+const attrMask = termbox.AttrBold | termbox.AttrUnderline | termbox.AttrReverse
+
+var colorNames = map[string]termbox.Attribute{
+	"default": termbox.ColorDefault,
+	"black":   termbox.ColorBlack,
+	"red":     termbox.ColorRed,
+	"green":   termbox.ColorGreen,
+	"yellow":  termbox.ColorYellow,
+	"blue":    termbox.ColorBlue,
+	"magenta": termbox.ColorMagenta,
+	"cyan":    termbox.ColorCyan,
+	"white":   termbox.ColorWhite,
+
+	"bold":      termbox.AttrBold,
+	"underline": termbox.AttrUnderline,
+	"reverse":   termbox.AttrReverse,
+}
+
+// ParseColor reads a color like "yellow", "red+bold" or "208", numbers are
+// colors of 256-color palette and need 256-color output mode
+func ParseColor(s string) (termbox.Attribute, error) {
+
+	var color termbox.Attribute
+
+	for _, part := range strings.Split(strings.ToLower(s), "+") {
+
+		part = strings.TrimSpace(part)
+
+		if attr, found := colorNames[part]; found {
+			color |= attr
+			continue
+		}
+
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || n > 255 {
+			return 0, fmt.Errorf("unknown color %q", s)
+		}
+
+		// palette colors are numbered from 1, zero is the default color
+		color |= termbox.Attribute(n + 1)
+	}
+
+	return color, nil
+}
+
+// Plain returns a color without text attributes, it is used for backgrounds
+func Plain(color termbox.Attribute) termbox.Attribute {
+	return color &^ attrMask
+}
+
+// FindTheme returns a theme with a given name, themes take precedence over
+// built-in themes with the same name
+func FindTheme(name string, themes []Theme) (Theme, error) {
+	for _, list := range [][]Theme{themes, Themes} {
+		for _, theme := range list {
+			if theme.Name == name {
+				return theme, nil
+			}
+		}
+	}
+	return Theme{}, fmt.Errorf("unknown theme %q", name)
+}
+
+// colors maps color names used in theme files to theme fields
+func (t *Theme) colors() map[string]*termbox.Attribute {
+	return map[string]*termbox.Attribute{
+		"board":        &t.Board.BoardColor,
+		"board_bg":     &t.Board.BoardBg,
+		"labels":       &t.Board.LabelsColor,
+		"labels_bg":    &t.Board.LabelsBg,
+		"x":            &t.Board.XColor,
+		"o":            &t.Board.OColor,
+		"win":          &t.Board.WinColor,
+		"win_bg":       &t.Board.WinBg,
+		"cursor":       &t.CursorFg,
+		"cursor_bg":    &t.CursorBg,
+		"text":         &t.TextFg,
+		"text_bg":      &t.TextBg,
+		"accent":       &t.AccentFg,
+		"selected":     &t.SelectedFg,
+		"selected_bg":  &t.SelectedBg,
+		"hint":         &t.HintFg,
+		"hint_bg":      &t.HintBg,
+		"candidate":    &t.CandidateFg,
+		"candidate_bg": &t.CandidateBg,
+	}
+}
+
+type themeFile struct {
+	Themes []struct {
+		Name    string            `json:"name"`
+		Base    string            `json:"base"`
+		Colors  map[string]string `json:"colors"`
+		Heatmap []string          `json:"heatmap"`
+	} `json:"themes"`
+}
+
+// LoadThemes reads themes from a JSON document, each theme starts as a copy of
+// its base theme, dark if not set, and overrides some of its colors:
+//
+//	{"themes": [{"name": "sea", "base": "dark", "colors": {"board_bg": "cyan", "x": "red+bold"}}]}
+func LoadThemes(r io.Reader) ([]Theme, error) {
+
+	var file themeFile
+
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("malformed themes: %v", err)
+	}
+
+	themes := []Theme{}
+
+	for _, t := range file.Themes {
+
+		if t.Name == "" {
+			return nil, fmt.Errorf("theme has no name")
+		}
+
+		if t.Base == "" {
+			t.Base = Themes[0].Name
+		}
+
+		// themes may be based on themes defined earlier in the file
+		theme, err := FindTheme(t.Base, themes)
+		if err != nil {
+			return nil, fmt.Errorf("theme %s: %v", t.Name, err)
+		}
+
+		theme.Name = t.Name
+		fields := theme.colors()
+
+		for key, value := range t.Colors {
+			field, found := fields[key]
+			if !found {
+				return nil, fmt.Errorf("theme %s: unknown color %q", t.Name, key)
+			}
+			if *field, err = ParseColor(value); err != nil {
+				return nil, fmt.Errorf("theme %s: %v", t.Name, err)
+			}
+		}
+
+		if len(t.Heatmap) != 0 {
+			theme.Heatmap = make([]termbox.Attribute, len(t.Heatmap))
+			for i, value := range t.Heatmap {
+				if theme.Heatmap[i], err = ParseColor(value); err != nil {
+					return nil, fmt.Errorf("theme %s: %v", t.Name, err)
+				}
+			}
+		}
+
+		themes = append(themes, theme)
+	}
+
+	return themes, nil
+}
+
+// LoadThemesFromFile reads themes from a file written in LoadThemes format
+func LoadThemesFromFile(fileName string) ([]Theme, error) {
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return LoadThemes(f)
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestParseColor(t *testing.T) {
+
+	for s, expected := range map[string]termbox.Attribute{
+		"yellow":         termbox.ColorYellow,
+		"Red + Bold":     termbox.ColorRed | termbox.AttrBold,
+		"default":        termbox.ColorDefault,
+		"reverse":        termbox.AttrReverse,
+		"0":              termbox.Attribute(1),
+		"208+underline":  termbox.Attribute(209) | termbox.AttrUnderline,
+		"255":            termbox.Attribute(256),
+		"blue+bold+bold": termbox.ColorBlue | termbox.AttrBold,
+	} {
+		color, err := ParseColor(s)
+		if err != nil || color != expected {
+			t.Errorf("%q: got %v, %v, expected %v", s, color, err, expected)
+		}
+	}
+
+	for _, s := range []string{"", "pink", "256", "-1", "red+"} {
+		if _, err := ParseColor(s); err == nil {
+			t.Errorf("%q: error expected", s)
+		}
+	}
+
+	if Plain(termbox.ColorRed|termbox.AttrBold|termbox.AttrReverse) != termbox.ColorRed {
+		t.Error("attributes are not removed")
+	}
+}
+
+func TestLoadThemes(t *testing.T) {
+
+	themes, err := LoadThemes(strings.NewReader(`{"themes": [
+		{"name": "sea", "colors": {"board_bg": "cyan", "x": "red+bold", "win_bg": "17"}},
+		{"name": "deep sea", "base": "sea", "colors": {"board_bg": "blue"}, "heatmap": ["black", "white"]},
+		{"name": "dark", "base": "light"}
+	]}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(themes) != 3 {
+		t.Fatalf("got %d themes, expected 3", len(themes))
+	}
+
+	sea, deep := themes[0], themes[1]
+
+	// unset base is the default theme
+	if sea.Board.BoardBg != termbox.ColorCyan || sea.Board.XColor != termbox.ColorRed|termbox.AttrBold ||
+		sea.Board.WinBg != termbox.Attribute(18) || sea.Board.OColor != Themes[0].Board.OColor {
+		t.Errorf("unexpected sea theme: %+v", sea)
+	}
+
+	// themes may be based on custom themes
+	if deep.Board.BoardBg != termbox.ColorBlue || deep.Board.XColor != sea.Board.XColor ||
+		len(deep.Heatmap) != 2 || deep.Heatmap[1] != termbox.ColorWhite {
+		t.Errorf("unexpected deep sea theme: %+v", deep)
+	}
+
+	// custom themes override built-in themes
+	dark, err := FindTheme("dark", themes)
+	if err != nil || dark.TextBg != termbox.ColorWhite {
+		t.Errorf("got %+v, %v, expected a copy of light theme", dark, err)
+	}
+
+	if light, err := FindTheme("light", themes); err != nil || light.Name != "light" {
+		t.Errorf("got %+v, %v, expected built-in light theme", light, err)
+	}
+
+	if _, err := FindTheme("sky", themes); err == nil {
+		t.Error("error expected for unknown theme")
+	}
+
+	for _, bad := range []string{
+		`{"themes": [`,
+		`{"themes": [{"colors": {"x": "red"}}]}`,
+		`{"themes": [{"name": "a", "base": "b"}]}`,
+		`{"themes": [{"name": "a", "colors": {"stones": "red"}}]}`,
+		`{"themes": [{"name": "a", "colors": {"x": "pink"}}]}`,
+		`{"themes": [{"name": "a", "heatmap": ["red", "1000"]}]}`,
+	} {
+		if _, err := LoadThemes(strings.NewReader(bad)); err == nil {
+			t.Errorf("%s: error expected", bad)
+		}
+	}
+}